@@ -0,0 +1,356 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"github.com/Achiket123/html2docx/converter"
+)
+
+// hashFile is the file in the output directory recording, for each input
+// converted, the hash of the settings it was converted with and, with
+// -skip hash, of its content.
+const hashFile = ".html2docx-hashes.json"
+
+// batchChunk is how many files per worker are read and converted at a time,
+// so a large tree need not fit in memory at once.
+const batchChunk = 4
+
+// batchFile is one input file of a batch and where its outputs go.
+type batchFile struct {
+	path    string // input file
+	rel     string // path relative to the input root, mirrored in the output directory
+	outputs []converter.Output
+	hash    string // hash of the settings, and of the content with -skip hash
+}
+
+// runBatch runs the batch subcommand: it converts every HTML file in the
+// given directories or matching the given globs into an output directory
+// that mirrors the input tree.
+func runBatch(args []string, stdout, stderr io.Writer) error {
+	fs := flag.NewFlagSet("batch", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() {
+		fmt.Fprintln(stderr, "Usage: html2docx batch [flags] -o <dir> <dir|glob> ...")
+		fmt.Fprintln(stderr, "\nConverts each HTML file in the directories, or matching the globs, to its own document in the output directory.")
+		fmt.Fprintln(stderr, "\nFlags:")
+		fs.PrintDefaults()
+	}
+	var flags optionFlags
+	formatList := fs.String("f", "", "output `formats`, comma separated: docx, pdf, md (default from the config, else docx)")
+	outDir := fs.String("o", "", "output `directory`, required")
+	workers := fs.Int("j", 0, "number of files converted at once (default GOMAXPROCS)")
+	skip := fs.String("skip", "mtime", "skip unchanged files: mtime (outputs newer than the input), hash (input content unchanged) or none")
+	flags.register(fs)
+	if err := fs.Parse(args); err != nil {
+		return flagError(err)
+	}
+
+	switch {
+	case *outDir == "":
+		return usageErrorf("missing output directory (-o)")
+	case fs.NArg() == 0:
+		return usageErrorf("missing input directory or glob")
+	case *skip != "mtime" && *skip != "hash" && *skip != "none":
+		return usageErrorf("invalid -skip %q, want mtime, hash or none", *skip)
+	}
+	opts, page, err := flags.options(fs)
+	if err != nil {
+		return err
+	}
+	if *formatList == "" {
+		*formatList = flags.defaultFormat()
+	}
+	formats, err := parseFormats(*formatList)
+	if err != nil {
+		return err
+	}
+	if len(formats) == 0 {
+		return usageErrorf("missing output format (-f)")
+	}
+
+	files, err := findInputs(fs.Args())
+	if err != nil {
+		return err
+	}
+	if err := planOutputs(files, *outDir, formats); err != nil {
+		return err
+	}
+
+	hashes := map[string]string{}
+	if *skip != "none" {
+		if hashes, err = readHashes(*outDir); err != nil {
+			return err
+		}
+	}
+	settings := settingsHash(opts, page, formats)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	converted, skipped, failed, warnings := 0, 0, 0, 0
+	fail := func(f *batchFile, err error) {
+		fmt.Fprintf(stderr, "failed: %s: %v\n", f.rel, err)
+		delete(hashes, f.rel)
+		failed++
+	}
+	chunk := *workers
+	if chunk <= 0 {
+		chunk = runtime.GOMAXPROCS(0)
+	}
+	chunk *= batchChunk
+	for start := 0; start < len(files); start += chunk {
+		var jobs []converter.Job
+		var pending []*batchFile
+		for _, f := range files[start:min(start+chunk, len(files))] {
+			if err := ctx.Err(); err != nil {
+				fail(f, fmt.Errorf("conversion stopped: %w", err))
+				continue
+			}
+			content, err := readInput(f.path, nil)
+			if err != nil {
+				fail(f, err)
+				continue
+			}
+			f.hash = settings
+			if *skip == "hash" {
+				f.hash = contentHash(settings, content)
+			}
+			if upToDate(f, *skip, hashes) {
+				skipped++
+				continue
+			}
+			for _, out := range f.outputs {
+				if err := os.MkdirAll(filepath.Dir(out.Path), 0755); err != nil {
+					return fmt.Errorf("failed to create output directory: %w", err)
+				}
+			}
+			// Relative images are found next to the file first, as a
+			// browser opening it would, then in the configured directories.
+			fileOpts := opts
+			fileOpts.ResourceDirs = append([]string{filepath.Dir(f.path)}, opts.ResourceDirs...)
+			jobs = append(jobs, converter.Job{
+				Sections: []converter.Section{{HTML: content, Page: page}},
+				Outputs:  f.outputs,
+				Options:  fileOpts,
+			})
+			pending = append(pending, f)
+		}
+
+		for i, res := range converter.ConvertBatch(ctx, jobs, *workers) {
+			f := pending[i]
+			for _, out := range res.Outputs {
+				for _, w := range out.Warnings {
+					fmt.Fprintf(stderr, "warning: %s: %s: %s\n", f.rel, out.Format, w)
+					warnings++
+				}
+			}
+			if res.Err != nil {
+				fail(f, res.Err)
+				continue
+			}
+			hashes[f.rel] = f.hash
+			converted++
+		}
+	}
+
+	if *skip != "none" {
+		if err := writeHashes(*outDir, hashes); err != nil {
+			return err
+		}
+	}
+	fmt.Fprintf(stdout, "%d converted, %d skipped, %d failed, %d warnings\n", converted, skipped, failed, warnings)
+	if failed > 0 {
+		return fmt.Errorf("%d of %d files failed", failed, len(files)-skipped)
+	}
+	return nil
+}
+
+// findInputs returns the HTML files in the directories, or matching the
+// globs, given as arguments, each with its path relative to the directory
+// or the fixed part of the glob.
+func findInputs(args []string) ([]*batchFile, error) {
+	var files []*batchFile
+	seen := map[string]bool{}
+	add := func(path, root string) error {
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return fmt.Errorf("failed to resolve %s: %w", path, err)
+		}
+		if abs, err := filepath.Abs(path); err == nil {
+			if seen[abs] {
+				return nil
+			}
+			seen[abs] = true
+		}
+		files = append(files, &batchFile{path: path, rel: rel})
+		return nil
+	}
+
+	for _, arg := range args {
+		if fi, err := os.Stat(arg); err == nil && fi.IsDir() {
+			err := filepath.WalkDir(arg, func(path string, d fs.DirEntry, err error) error {
+				if err != nil {
+					return err
+				}
+				if d.IsDir() || !isHTMLFile(path) {
+					return nil
+				}
+				return add(path, arg)
+			})
+			if err != nil {
+				return nil, fmt.Errorf("failed to read input directory: %w", err)
+			}
+			continue
+		}
+
+		matches, err := filepath.Glob(arg)
+		if err != nil {
+			return nil, usageErrorf("invalid glob %q", arg)
+		}
+		if len(matches) == 0 {
+			return nil, fmt.Errorf("no files match %s", arg)
+		}
+		root := globRoot(arg)
+		for _, path := range matches {
+			if fi, err := os.Stat(path); err != nil || fi.IsDir() {
+				continue
+			}
+			if err := add(path, root); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return files, nil
+}
+
+// isHTMLFile reports whether a file name has an HTML extension.
+func isHTMLFile(name string) bool {
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".html", ".htm", ".xhtml":
+		return true
+	}
+	return false
+}
+
+// globRoot returns the directory holding everything a glob can match: its
+// leading path elements without wildcards.
+func globRoot(pattern string) string {
+	dir := filepath.Dir(pattern)
+	for strings.ContainsAny(dir, "*?[") && dir != filepath.Dir(dir) {
+		dir = filepath.Dir(dir)
+	}
+	if strings.ContainsAny(dir, "*?[") {
+		return "."
+	}
+	return dir
+}
+
+// planOutputs sets the outputs of each file: its relative path under outDir
+// with the extension of each format. Two inputs writing the same output are
+// an error.
+func planOutputs(files []*batchFile, outDir string, formats []converter.Format) error {
+	owners := map[string]string{}
+	for _, f := range files {
+		base := strings.TrimSuffix(f.rel, filepath.Ext(f.rel))
+		for _, format := range formats {
+			path := filepath.Join(outDir, base+"."+format.Extensions[0])
+			if other, ok := owners[path]; ok {
+				return fmt.Errorf("%s and %s would both be written to %s", other, f.path, path)
+			}
+			owners[path] = f.path
+			f.outputs = append(f.outputs, converter.Output{Format: format.Name, Path: path})
+		}
+	}
+	return nil
+}
+
+// settingsHash returns the hash of what decides the outputs besides the
+// input: the formats, the converter options and the page setup, whether
+// given by flags or the config file. Changing any of them converts every
+// file again.
+func settingsHash(opts converter.Options, page converter.PageSetup, formats []converter.Format) string {
+	h := sha256.New()
+	for _, f := range formats {
+		io.WriteString(h, f.Name+"\x00")
+	}
+	json.NewEncoder(h).Encode(struct {
+		Options converter.Options
+		Page    converter.PageSetup
+	}{opts, page})
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// contentHash returns the hash recorded for an input with -skip hash: that
+// of its content and the settings.
+func contentHash(settings, content string) string {
+	h := sha256.New()
+	io.WriteString(h, settings+"\x00")
+	io.WriteString(h, content)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// upToDate reports whether every output of a file exists, is current for the
+// skip mode and was written with the same settings.
+func upToDate(f *batchFile, skip string, hashes map[string]string) bool {
+	if skip == "none" {
+		return false
+	}
+	in, err := os.Stat(f.path)
+	if err != nil {
+		return false
+	}
+	for _, out := range f.outputs {
+		fi, err := os.Stat(out.Path)
+		if err != nil {
+			return false
+		}
+		if skip == "mtime" && fi.ModTime().Before(in.ModTime()) {
+			return false
+		}
+	}
+	return hashes[f.rel] == f.hash
+}
+
+// readHashes reads the input hashes recorded in an output directory. A
+// missing file records none.
+func readHashes(dir string) (map[string]string, error) {
+	hashes := map[string]string{}
+	data, err := os.ReadFile(filepath.Join(dir, hashFile))
+	if errors.Is(err, fs.ErrNotExist) {
+		return hashes, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", hashFile, err)
+	}
+	if err := json.Unmarshal(data, &hashes); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", hashFile, err)
+	}
+	return hashes, nil
+}
+
+// writeHashes records the input hashes in an output directory.
+func writeHashes(dir string, hashes map[string]string) error {
+	data, err := json.MarshalIndent(hashes, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode %s: %w", hashFile, err)
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create output directory: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, hashFile), append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", hashFile, err)
+	}
+	return nil
+}
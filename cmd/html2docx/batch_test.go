@@ -0,0 +1,228 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/png"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func writeTree(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestBatchDirectory(t *testing.T) {
+	in, out := t.TempDir(), t.TempDir()
+	writeTree(t, in, map[string]string{
+		"index.html":        "<h1>Index</h1>",
+		"guide/intro.htm":   "<h1>Intro</h1><iframe></iframe>",
+		"guide/notes.txt":   "not HTML",
+		"guide/deep/a.html": "<p>A</p>",
+	})
+
+	var stdout, stderr strings.Builder
+	args := []string{"batch", "-f", "md,pdf", "-o", out, in}
+	if code := run(args, nil, &stdout, &stderr); code != 0 {
+		t.Fatalf("exit code %d: %s", code, stderr.String())
+	}
+	for _, name := range []string{"index.md", "index.pdf", "guide/intro.md", "guide/intro.pdf", "guide/deep/a.md", "guide/deep/a.pdf"} {
+		if _, err := os.Stat(filepath.Join(out, name)); err != nil {
+			t.Errorf("expected %s to be written: %v", name, err)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(out, "guide/notes.md")); err == nil {
+		t.Error("expected non-HTML files to be ignored")
+	}
+	if want := "3 converted, 0 skipped, 0 failed, 2 warnings"; !strings.Contains(stdout.String(), want) {
+		t.Errorf("expected summary %q, got %q", want, stdout.String())
+	}
+	if !strings.Contains(stderr.String(), "warning: "+filepath.Join("guide", "intro.htm")+": markdown: <iframe>") {
+		t.Errorf("expected the iframe to be reported with its file, got %q", stderr.String())
+	}
+
+	// A second run skips the files whose outputs are newer.
+	stdout.Reset()
+	if code := run(args, nil, &stdout, &stderr); code != 0 {
+		t.Fatalf("exit code %d: %s", code, stderr.String())
+	}
+	if want := "0 converted, 3 skipped"; !strings.Contains(stdout.String(), want) {
+		t.Errorf("expected summary %q, got %q", want, stdout.String())
+	}
+
+	// Touching an input converts it again.
+	later := time.Now().Add(time.Hour)
+	os.Chtimes(filepath.Join(in, "index.html"), later, later)
+	stdout.Reset()
+	run(args, nil, &stdout, &stderr)
+	if want := "1 converted, 2 skipped"; !strings.Contains(stdout.String(), want) {
+		t.Errorf("expected summary %q, got %q", want, stdout.String())
+	}
+}
+
+func TestBatchGlobHash(t *testing.T) {
+	in, out := t.TempDir(), t.TempDir()
+	writeTree(t, in, map[string]string{
+		"a.html": "<p>A</p>",
+		"b.html": "<p>B</p>",
+	})
+
+	args := []string{"batch", "-f", "md", "-skip", "hash", "-o", out, filepath.Join(in, "*.html")}
+	var stdout, stderr strings.Builder
+	if code := run(args, nil, &stdout, &stderr); code != 0 {
+		t.Fatalf("exit code %d: %s", code, stderr.String())
+	}
+	if _, err := os.Stat(filepath.Join(out, "a.md")); err != nil {
+		t.Errorf("expected a.md next to b.md: %v", err)
+	}
+
+	// Only content changes count, not modification times.
+	later := time.Now().Add(time.Hour)
+	os.Chtimes(filepath.Join(in, "a.html"), later, later)
+	os.WriteFile(filepath.Join(in, "b.html"), []byte("<p>B2</p>"), 0644)
+	stdout.Reset()
+	run(args, nil, &stdout, &stderr)
+	if want := "1 converted, 1 skipped"; !strings.Contains(stdout.String(), want) {
+		t.Errorf("expected summary %q, got %q", want, stdout.String())
+	}
+	if md, _ := os.ReadFile(filepath.Join(out, "b.md")); string(md) != "B2" {
+		t.Errorf("expected b.md to be converted again, got %q", md)
+	}
+}
+
+func TestBatchOptionsChange(t *testing.T) {
+	for _, skip := range []string{"mtime", "hash"} {
+		in, out := t.TempDir(), t.TempDir()
+		writeTree(t, in, map[string]string{"a.html": "<p>A</p>"})
+
+		args := []string{"batch", "-f", "pdf", "-skip", skip, "-o", out, in}
+		var stdout, stderr strings.Builder
+		if code := run(args, nil, &stdout, &stderr); code != 0 {
+			t.Fatalf("-skip %s: exit code %d: %s", skip, code, stderr.String())
+		}
+
+		// A different page size converts the file again, then skips it.
+		args = append([]string{"batch", "-page-size", "A5"}, args[1:]...)
+		for _, want := range []string{"1 converted, 0 skipped", "0 converted, 1 skipped"} {
+			stdout.Reset()
+			if code := run(args, nil, &stdout, &stderr); code != 0 {
+				t.Fatalf("-skip %s: exit code %d: %s", skip, code, stderr.String())
+			}
+			if !strings.Contains(stdout.String(), want) {
+				t.Errorf("-skip %s: expected summary %q, got %q", skip, want, stdout.String())
+			}
+		}
+	}
+}
+
+func TestBatchChunks(t *testing.T) {
+	in, out := t.TempDir(), t.TempDir()
+	files := map[string]string{}
+	for i := 0; i < 2*batchChunk+1; i++ {
+		files[fmt.Sprintf("page%d.html", i)] = fmt.Sprintf("<p>Page %d</p><iframe></iframe>", i)
+	}
+	writeTree(t, in, files)
+
+	var stdout, stderr strings.Builder
+	if code := run([]string{"batch", "-f", "md", "-j", "1", "-o", out, in}, nil, &stdout, &stderr); code != 0 {
+		t.Fatalf("exit code %d: %s", code, stderr.String())
+	}
+	want := fmt.Sprintf("%d converted, 0 skipped, 0 failed, %d warnings", len(files), len(files))
+	if !strings.Contains(stdout.String(), want) {
+		t.Errorf("expected summary %q, got %q", want, stdout.String())
+	}
+	for name := range files {
+		md, _ := os.ReadFile(filepath.Join(out, strings.TrimSuffix(name, ".html")+".md"))
+		if want := strings.TrimSuffix(strings.TrimPrefix(name, "page"), ".html"); !strings.HasSuffix(string(md), want) {
+			t.Errorf("expected %s to be converted, got %q", name, md)
+		}
+	}
+}
+
+func TestBatchFailures(t *testing.T) {
+	in, out := t.TempDir(), t.TempDir()
+	writeTree(t, in, map[string]string{
+		"ok.html":  "<p>fine</p>",
+		"bad.html": "<iframe></iframe>",
+	})
+
+	var stdout, stderr strings.Builder
+	code := run([]string{"batch", "-f", "md", "-strict", "-o", out, in}, nil, &stdout, &stderr)
+	if code != 1 {
+		t.Fatalf("expected exit code 1, got %d", code)
+	}
+	if !strings.Contains(stderr.String(), "failed: bad.html") || !strings.Contains(stderr.String(), "1 of 2 files failed") {
+		t.Errorf("expected the failure to be reported, got %q", stderr.String())
+	}
+	if want := "1 converted, 0 skipped, 1 failed"; !strings.Contains(stdout.String(), want) {
+		t.Errorf("expected summary %q, got %q", want, stdout.String())
+	}
+}
+
+func TestBatchUnreadableFile(t *testing.T) {
+	in, out := t.TempDir(), t.TempDir()
+	writeTree(t, in, map[string]string{"ok.html": "<p>fine</p>"})
+	if err := os.Symlink(filepath.Join(in, "missing"), filepath.Join(in, "broken.html")); err != nil {
+		t.Skipf("cannot create a symbolic link: %v", err)
+	}
+
+	var stdout, stderr strings.Builder
+	code := run([]string{"batch", "-f", "md", "-o", out, in}, nil, &stdout, &stderr)
+	if code != 1 {
+		t.Fatalf("expected exit code 1, got %d", code)
+	}
+	if !strings.Contains(stderr.String(), "failed: broken.html: ") || !strings.Contains(stderr.String(), "1 of 2 files failed") {
+		t.Errorf("expected the unreadable file to be reported, got %q", stderr.String())
+	}
+	if want := "1 converted, 0 skipped, 1 failed"; !strings.Contains(stdout.String(), want) {
+		t.Errorf("expected the other file to be converted, summary %q, got %q", want, stdout.String())
+	}
+}
+
+func TestBatchRelativeImages(t *testing.T) {
+	in, out := t.TempDir(), t.TempDir()
+	var logo bytes.Buffer
+	png.Encode(&logo, image.NewGray(image.Rect(0, 0, 4, 4)))
+	writeTree(t, in, map[string]string{
+		"guide/page.html":    `<img src="img/logo.png">`,
+		"guide/img/logo.png": logo.String(),
+	})
+
+	var stdout, stderr strings.Builder
+	if code := run([]string{"batch", "-f", "pdf", "-o", out, in}, nil, &stdout, &stderr); code != 0 {
+		t.Fatalf("exit code %d: %s", code, stderr.String())
+	}
+	if stderr.Len() > 0 {
+		t.Errorf("expected the image next to the page to load, got %q", stderr.String())
+	}
+	if data, _ := os.ReadFile(filepath.Join(out, "guide", "page.pdf")); !bytes.Contains(data, []byte("/Subtype /Image")) {
+		t.Error("expected the image in the document")
+	}
+}
+
+func TestGlobRoot(t *testing.T) {
+	tests := []struct{ pattern, want string }{
+		{"docs/*.html", "docs"},
+		{"docs/*/index.html", "docs"},
+		{"*.html", "."},
+		{"site/pages/[a-c]*/*.htm", filepath.Join("site", "pages")},
+	}
+	for _, tc := range tests {
+		if got := globRoot(filepath.FromSlash(tc.pattern)); got != tc.want {
+			t.Errorf("globRoot(%q) = %q, want %q", tc.pattern, got, tc.want)
+		}
+	}
+}
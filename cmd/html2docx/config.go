@@ -0,0 +1,211 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/Achiket123/html2docx/converter"
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// configNames are the config files looked for in the working directory when
+// -config is not given, in order of preference.
+var configNames = []string{".html2docx.yaml", ".html2docx.yml", ".html2docx.toml", ".html2docx.json"}
+
+// config is the content of a config file. Every field is optional, and
+// command-line flags override it. Relative paths are resolved against the
+// directory of the file.
+type config struct {
+	Format    string                     `yaml:"format" json:"format" toml:"format"`          // default output format
+	Page      pageConfig                 `yaml:"page" json:"page" toml:"page"`                // page setup
+	Font      fontConfig                 `yaml:"font" json:"font" toml:"font"`                // default font
+	Fonts     map[string]fontFilesConfig `yaml:"fonts" json:"fonts" toml:"fonts"`             // TrueType families to embed in PDF
+	Classes   map[string]styleConfig     `yaml:"classes" json:"classes" toml:"classes"`       // formatting by class name
+	Resources []string                   `yaml:"resources" json:"resources" toml:"resources"` // directories to find images in
+	Metadata  metadataConfig             `yaml:"metadata" json:"metadata" toml:"metadata"`
+	Strict    bool                       `yaml:"strict" json:"strict" toml:"strict"`
+}
+
+type pageConfig struct {
+	Size    string `yaml:"size" json:"size" toml:"size"`          // as the -page-size flag
+	Margins string `yaml:"margins" json:"margins" toml:"margins"` // as the -margins flag
+}
+
+type fontConfig struct {
+	Family string  `yaml:"family" json:"family" toml:"family"`
+	Size   float64 `yaml:"size" json:"size" toml:"size"` // in points
+}
+
+type fontFilesConfig struct {
+	Regular    string `yaml:"regular" json:"regular" toml:"regular"`
+	Bold       string `yaml:"bold" json:"bold" toml:"bold"`
+	Italic     string `yaml:"italic" json:"italic" toml:"italic"`
+	BoldItalic string `yaml:"bold_italic" json:"bold_italic" toml:"bold_italic"`
+}
+
+type styleConfig struct {
+	Bold      bool    `yaml:"bold" json:"bold" toml:"bold"`
+	Italic    bool    `yaml:"italic" json:"italic" toml:"italic"`
+	Underline bool    `yaml:"underline" json:"underline" toml:"underline"`
+	Code      bool    `yaml:"code" json:"code" toml:"code"`
+	Color     string  `yaml:"color" json:"color" toml:"color"` // e.g. "#555555"
+	Font      string  `yaml:"font" json:"font" toml:"font"`    // font family
+	Size      float64 `yaml:"size" json:"size" toml:"size"`    // in points
+}
+
+type metadataConfig struct {
+	Title       string   `yaml:"title" json:"title" toml:"title"`
+	Author      string   `yaml:"author" json:"author" toml:"author"`
+	Subject     string   `yaml:"subject" json:"subject" toml:"subject"`
+	Description string   `yaml:"description" json:"description" toml:"description"`
+	Keywords    []string `yaml:"keywords" json:"keywords" toml:"keywords"`
+}
+
+// loadConfig reads the config file at path, or when path is empty the first
+// of configNames found in the working directory. Without a file it returns
+// an empty config.
+func loadConfig(path string) (*config, error) {
+	if path == "" {
+		for _, name := range configNames {
+			if _, err := os.Stat(name); err == nil {
+				path = name
+				break
+			}
+		}
+		if path == "" {
+			return &config{}, nil
+		}
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config: %w", err)
+	}
+	cfg, err := parseConfig(data, filepath.Ext(path))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse config %s: %w", path, err)
+	}
+	if err := cfg.validate(); err != nil {
+		return nil, fmt.Errorf("invalid config %s: %w", path, err)
+	}
+	cfg.resolvePaths(filepath.Dir(path))
+	return cfg, nil
+}
+
+// parseConfig decodes a config file in the format given by its extension.
+// Unknown keys are errors, so misspelled settings do not go unnoticed.
+func parseConfig(data []byte, ext string) (*config, error) {
+	cfg := &config{}
+	switch strings.ToLower(ext) {
+	case ".yaml", ".yml":
+		dec := yaml.NewDecoder(bytes.NewReader(data))
+		dec.KnownFields(true)
+		if err := dec.Decode(cfg); err != nil && !errors.Is(err, io.EOF) {
+			return nil, err
+		}
+	case ".json":
+		dec := json.NewDecoder(bytes.NewReader(data))
+		dec.DisallowUnknownFields()
+		if err := dec.Decode(cfg); err != nil {
+			return nil, err
+		}
+	case ".toml":
+		md, err := toml.Decode(string(data), cfg)
+		if err != nil {
+			return nil, err
+		}
+		if undecoded := md.Undecoded(); len(undecoded) > 0 {
+			return nil, fmt.Errorf("unknown key %q", undecoded[0].String())
+		}
+	default:
+		return nil, fmt.Errorf("unknown config format %q, want .yaml, .json or .toml", ext)
+	}
+	return cfg, nil
+}
+
+// validate checks the settings that are parsed later, so that mistakes are
+// reported against the config file.
+func (cfg *config) validate() error {
+	if cfg.Format != "" {
+		if _, ok := converter.LookupFormat(cfg.Format); !ok {
+			return fmt.Errorf("unknown format %q", cfg.Format)
+		}
+	}
+	if cfg.Page.Size != "" {
+		if _, _, ok := converter.ParsePageSize(cfg.Page.Size); !ok {
+			return fmt.Errorf("invalid page size %q", cfg.Page.Size)
+		}
+	}
+	if cfg.Page.Margins != "" {
+		if _, ok := converter.ParseMargins(cfg.Page.Margins); !ok {
+			return fmt.Errorf("invalid margins %q", cfg.Page.Margins)
+		}
+	}
+	for family, files := range cfg.Fonts {
+		if files.Regular == "" {
+			return fmt.Errorf("font %q has no regular style file", family)
+		}
+	}
+	return nil
+}
+
+// resolvePaths makes the font files and resource directories relative to
+// dir.
+func (cfg *config) resolvePaths(dir string) {
+	resolve := func(path string) string {
+		if path == "" || filepath.IsAbs(path) {
+			return path
+		}
+		return filepath.Join(dir, path)
+	}
+	for family, files := range cfg.Fonts {
+		cfg.Fonts[family] = fontFilesConfig{
+			Regular:    resolve(files.Regular),
+			Bold:       resolve(files.Bold),
+			Italic:     resolve(files.Italic),
+			BoldItalic: resolve(files.BoldItalic),
+		}
+	}
+	for i, root := range cfg.Resources {
+		cfg.Resources[i] = resolve(root)
+	}
+}
+
+// classStyles returns the class mappings as converter styles.
+func (cfg *config) classStyles() map[string]converter.Style {
+	if len(cfg.Classes) == 0 {
+		return nil
+	}
+	styles := make(map[string]converter.Style, len(cfg.Classes))
+	for class, s := range cfg.Classes {
+		styles[class] = converter.Style{
+			Bold:       s.Bold,
+			Italic:     s.Italic,
+			Underline:  s.Underline,
+			Code:       s.Code,
+			Color:      s.Color,
+			FontFamily: s.Font,
+			FontSize:   s.Size,
+		}
+	}
+	return styles
+}
+
+// fonts returns the fonts to embed as converter font files.
+func (cfg *config) fonts() map[string]converter.FontFiles {
+	if len(cfg.Fonts) == 0 {
+		return nil
+	}
+	fonts := make(map[string]converter.FontFiles, len(cfg.Fonts))
+	for family, f := range cfg.Fonts {
+		fonts[family] = converter.FontFiles{Regular: f.Regular, Bold: f.Bold, Italic: f.Italic, BoldItalic: f.BoldItalic}
+	}
+	return fonts
+}
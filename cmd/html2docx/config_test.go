@@ -0,0 +1,202 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"image"
+	"image/png"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseConfig(t *testing.T) {
+	want := &config{
+		Format:    "pdf",
+		Page:      pageConfig{Size: "A4 landscape", Margins: "20mm"},
+		Font:      fontConfig{Family: "DejaVu Sans", Size: 10.5},
+		Fonts:     map[string]fontFilesConfig{"DejaVu Sans": {Regular: "fonts/DejaVuSans.ttf", BoldItalic: "fonts/DejaVuSans-BoldOblique.ttf"}},
+		Classes:   map[string]styleConfig{"note": {Italic: true, Color: "#555555", Size: 9}},
+		Resources: []string{"assets"},
+		Metadata:  metadataConfig{Title: "Handbook", Keywords: []string{"a", "b"}},
+		Strict:    true,
+	}
+	files := map[string]string{
+		".yaml": `
+format: pdf
+page: {size: A4 landscape, margins: 20mm}
+font: {family: DejaVu Sans, size: 10.5}
+fonts:
+  DejaVu Sans:
+    regular: fonts/DejaVuSans.ttf
+    bold_italic: fonts/DejaVuSans-BoldOblique.ttf
+classes:
+  note: {italic: true, color: "#555555", size: 9}
+resources: [assets]
+metadata: {title: Handbook, keywords: [a, b]}
+strict: true
+`,
+		".json": `{
+  "format": "pdf",
+  "page": {"size": "A4 landscape", "margins": "20mm"},
+  "font": {"family": "DejaVu Sans", "size": 10.5},
+  "fonts": {"DejaVu Sans": {"regular": "fonts/DejaVuSans.ttf", "bold_italic": "fonts/DejaVuSans-BoldOblique.ttf"}},
+  "classes": {"note": {"italic": true, "color": "#555555", "size": 9}},
+  "resources": ["assets"],
+  "metadata": {"title": "Handbook", "keywords": ["a", "b"]},
+  "strict": true
+}`,
+		".toml": `
+format = "pdf"
+resources = ["assets"]
+strict = true
+
+[page]
+size = "A4 landscape"
+margins = "20mm"
+
+[font]
+family = "DejaVu Sans"
+size = 10.5
+
+[fonts."DejaVu Sans"]
+regular = "fonts/DejaVuSans.ttf"
+bold_italic = "fonts/DejaVuSans-BoldOblique.ttf"
+
+[classes.note]
+italic = true
+color = "#555555"
+size = 9
+
+[metadata]
+title = "Handbook"
+keywords = ["a", "b"]
+`,
+	}
+	for ext, content := range files {
+		got, err := parseConfig([]byte(content), ext)
+		if err != nil {
+			t.Errorf("%s: %v", ext, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("%s: expected %+v, got %+v", ext, want, got)
+		}
+	}
+}
+
+func TestLoadConfigErrors(t *testing.T) {
+	tests := []struct {
+		name, file, content, msg string
+	}{
+		{"unknown yaml key", "c.yaml", "pagesize: A4", "field pagesize not found"},
+		{"unknown json key", "c.json", `{"page": {"orientation": "landscape"}}`, "unknown field"},
+		{"unknown toml key", "c.toml", "[metadata]\nauthors = \"x\"", `unknown key "metadata.authors"`},
+		{"unknown extension", "c.ini", "format=pdf", "unknown config format"},
+		{"bad page size", "c.yaml", "page: {size: huge}", `invalid page size "huge"`},
+		{"bad format", "c.yaml", "format: odt", `unknown format "odt"`},
+		{"font without regular", "c.yaml", "fonts: {X: {bold: x.ttf}}", "no regular style file"},
+	}
+	dir := t.TempDir()
+	for _, tc := range tests {
+		path := filepath.Join(dir, tc.file)
+		os.WriteFile(path, []byte(tc.content), 0644)
+		if _, err := loadConfig(path); err == nil || !strings.Contains(err.Error(), tc.msg) {
+			t.Errorf("%s: expected an error containing %q, got %v", tc.name, tc.msg, err)
+		}
+	}
+	if _, err := loadConfig(filepath.Join(dir, "missing.yaml")); err == nil {
+		t.Error("expected an error for a missing config file")
+	}
+}
+
+func TestConvertConfig(t *testing.T) {
+	dir := t.TempDir()
+	t.Chdir(dir)
+	var logo bytes.Buffer
+	png.Encode(&logo, image.NewGray(image.Rect(0, 0, 4, 4)))
+	writeTree(t, dir, map[string]string{
+		".html2docx.yaml": `
+format: md
+classes:
+  note: {bold: true}
+resources: [assets]
+`,
+		"assets/logo.png": logo.String(),
+		"other.json":      `{"format": "md", "classes": {"note": {"italic": true}}}`,
+	})
+	input := `<p>A <span class="note">note</span></p><img src="logo.png" alt="Logo">`
+
+	var stdout, stderr strings.Builder
+	if code := run([]string{"convert"}, strings.NewReader(input), &stdout, &stderr); code != 0 {
+		t.Fatalf("exit code %d: %s", code, stderr.String())
+	}
+	if want := "A **note**"; !strings.Contains(stdout.String(), want) {
+		t.Errorf("expected the format and class style of the discovered config, %q in %q", want, stdout.String())
+	}
+
+	// --config replaces the discovered file.
+	stdout.Reset()
+	if code := run([]string{"convert", "--config", "other.json"}, strings.NewReader(input), &stdout, &stderr); code != 0 {
+		t.Fatalf("exit code %d: %s", code, stderr.String())
+	}
+	if want := "A *note*"; !strings.Contains(stdout.String(), want) {
+		t.Errorf("expected the class style of the given config, %q in %q", want, stdout.String())
+	}
+
+	// Flags override the config.
+	stdout.Reset()
+	if code := run([]string{"convert", "-f", "pdf"}, strings.NewReader(input), &stdout, &stderr); code != 0 {
+		t.Fatalf("exit code %d: %s", code, stderr.String())
+	}
+	if !strings.HasPrefix(stdout.String(), "%PDF") {
+		t.Errorf("expected -f to override the config format, got %.20q", stdout.String())
+	}
+	if !strings.Contains(stdout.String(), "/Subtype /Image") || stderr.Len() > 0 {
+		t.Errorf("expected the image found in the resource directory, got warnings %q", stderr.String())
+	}
+}
+
+func TestOptionFlagsConfig(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "c.toml")
+	os.WriteFile(path, []byte(`
+[page]
+size = "letter"
+margins = "1in"
+
+[font]
+family = "Georgia"
+size = 12
+
+[metadata]
+title = "From config"
+author = "Config"
+keywords = ["x", "y"]
+`), 0644)
+
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	var flags optionFlags
+	flags.register(fs)
+	if err := fs.Parse([]string{"-config", path, "-title", "From flag", "-font-size", "9"}); err != nil {
+		t.Fatal(err)
+	}
+	opts, page, err := flags.options(fs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if opts.Metadata.Title != "From flag" || opts.Metadata.Author != "Config" {
+		t.Errorf("expected the title from the flag and the author from the config, got %+v", opts.Metadata)
+	}
+	if opts.FontFamily != "Georgia" || opts.FontSize != 9 {
+		t.Errorf("expected Georgia 9pt, got %s %vpt", opts.FontFamily, opts.FontSize)
+	}
+	if !reflect.DeepEqual(opts.Metadata.Keywords, []string{"x", "y"}) {
+		t.Errorf("expected the config keywords, got %v", opts.Metadata.Keywords)
+	}
+	if page.Margins == nil || page.Margins.Top != 25.4 {
+		t.Errorf("expected the config margins, got %+v", page.Margins)
+	}
+}
@@ -0,0 +1,288 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+
+	"github.com/Achiket123/html2docx/converter"
+)
+
+// optionFlags are the flags setting converter options, shared by the
+// commands that convert files, and the config file they override.
+type optionFlags struct {
+	config      string
+	cfg         *config // loaded by options
+	pageSize    string
+	margins     string
+	font        string
+	fontSize    float64
+	title       string
+	author      string
+	subject     string
+	description string
+	keywords    string
+	strict      bool
+}
+
+// register adds the flags to fs.
+func (f *optionFlags) register(fs *flag.FlagSet) {
+	fs.StringVar(&f.config, "config", "", "config `file` (default "+strings.Join(configNames, ", ")+" in the working directory)")
+	fs.StringVar(&f.pageSize, "page-size", "", "page `size` such as A4, letter, \"A4 landscape\" or \"210mm 297mm\"")
+	fs.StringVar(&f.margins, "margins", "", "page `margins` as a CSS shorthand, e.g. 20mm or \"1in 0.75in\"")
+	fs.StringVar(&f.font, "font", "", "default font `family`")
+	fs.Float64Var(&f.fontSize, "font-size", 0, "default font size in `points`")
+	fs.StringVar(&f.title, "title", "", "document title")
+	fs.StringVar(&f.author, "author", "", "document author")
+	fs.StringVar(&f.subject, "subject", "", "document subject")
+	fs.StringVar(&f.description, "description", "", "document description")
+	fs.StringVar(&f.keywords, "keywords", "", "document keywords, comma separated")
+	fs.BoolVar(&f.strict, "strict", false, "fail on content that cannot be converted instead of warning")
+}
+
+// options loads the config file and returns the converter options and the
+// page setup given by the flags parsed by fs, or else by the config. The page
+// setup overrides the one in the HTML, so it is applied to each section
+// rather than as an option.
+func (f *optionFlags) options(fs *flag.FlagSet) (converter.Options, converter.PageSetup, error) {
+	var page converter.PageSetup
+	if err := f.loadConfig(fs); err != nil {
+		return converter.Options{}, page, err
+	}
+
+	if f.pageSize != "" {
+		size, orientation, ok := converter.ParsePageSize(f.pageSize)
+		if !ok {
+			return converter.Options{}, page, usageErrorf("invalid page size %q", f.pageSize)
+		}
+		page.Size, page.Orientation = size, orientation
+	}
+	if f.margins != "" {
+		m, ok := converter.ParseMargins(f.margins)
+		if !ok {
+			return converter.Options{}, page, usageErrorf("invalid margins %q", f.margins)
+		}
+		page.Margins = &m
+	}
+
+	opts := converter.Options{
+		FontFamily:   f.font,
+		FontSize:     f.fontSize,
+		Strict:       f.strict,
+		ClassStyles:  f.cfg.classStyles(),
+		Fonts:        f.cfg.fonts(),
+		ResourceDirs: f.cfg.Resources,
+		Metadata: converter.Metadata{
+			Title:       f.title,
+			Author:      f.author,
+			Subject:     f.subject,
+			Description: f.description,
+		},
+	}
+	for _, k := range strings.Split(f.keywords, ",") {
+		if k = strings.TrimSpace(k); k != "" {
+			opts.Metadata.Keywords = append(opts.Metadata.Keywords, k)
+		}
+	}
+	return opts, page, nil
+}
+
+// loadConfig loads the config file and takes the settings of the flags not
+// given on the command line from it.
+func (f *optionFlags) loadConfig(fs *flag.FlagSet) error {
+	cfg, err := loadConfig(f.config)
+	if err != nil {
+		return err
+	}
+	f.cfg = cfg
+
+	set := map[string]bool{}
+	fs.Visit(func(fl *flag.Flag) { set[fl.Name] = true })
+	for _, s := range []struct {
+		flag  string
+		value *string
+		cfg   string
+	}{
+		{"page-size", &f.pageSize, cfg.Page.Size},
+		{"margins", &f.margins, cfg.Page.Margins},
+		{"font", &f.font, cfg.Font.Family},
+		{"title", &f.title, cfg.Metadata.Title},
+		{"author", &f.author, cfg.Metadata.Author},
+		{"subject", &f.subject, cfg.Metadata.Subject},
+		{"description", &f.description, cfg.Metadata.Description},
+		{"keywords", &f.keywords, strings.Join(cfg.Metadata.Keywords, ",")},
+	} {
+		if !set[s.flag] && s.cfg != "" {
+			*s.value = s.cfg
+		}
+	}
+	if !set["font-size"] && cfg.Font.Size > 0 {
+		f.fontSize = cfg.Font.Size
+	}
+	if !set["strict"] && cfg.Strict {
+		f.strict = true
+	}
+	return nil
+}
+
+// convertFlags are the flags of the convert subcommand.
+type convertFlags struct {
+	optionFlags
+	formats string
+	output  string
+}
+
+// register adds the flags to fs.
+func (f *convertFlags) register(fs *flag.FlagSet) {
+	fs.StringVar(&f.formats, "f", "", "output `formats`, comma separated: docx, pdf, md (default from -o, else the config, else docx)")
+	fs.StringVar(&f.formats, "format", "", "same as -f")
+	fs.StringVar(&f.output, "o", "", "output `file`, - for stdout; with several formats its extension is replaced per format")
+	fs.StringVar(&f.output, "output", "", "same as -o")
+	f.optionFlags.register(fs)
+}
+
+// outputs returns where each requested format is written.
+func (f *convertFlags) outputs(stdout io.Writer) ([]converter.Output, error) {
+	formats, err := parseFormats(f.formats)
+	if err != nil {
+		return nil, err
+	}
+	if len(formats) == 0 {
+		format, ok := converter.FormatForFile(f.output)
+		if !ok {
+			format, _ = converter.LookupFormat(f.defaultFormat())
+		}
+		formats = append(formats, format)
+	}
+
+	if len(formats) == 1 {
+		if f.output == "" || f.output == "-" {
+			return []converter.Output{{Format: formats[0].Name, Writer: stdout}}, nil
+		}
+		return []converter.Output{{Format: formats[0].Name, Path: f.output}}, nil
+	}
+
+	if f.output == "" || f.output == "-" {
+		return nil, usageErrorf("several formats need an output file name (-o)")
+	}
+	base := f.output
+	if _, ok := converter.FormatForFile(base); ok {
+		base = strings.TrimSuffix(base, filepath.Ext(base))
+	}
+	outputs := make([]converter.Output, len(formats))
+	for i, format := range formats {
+		outputs[i] = converter.Output{Format: format.Name, Path: base + "." + format.Extensions[0]}
+	}
+	return outputs, nil
+}
+
+// defaultFormat returns the format used when neither -f nor the output file
+// name gives one: the config's, or else DOCX.
+func (f *optionFlags) defaultFormat() string {
+	if f.cfg != nil && f.cfg.Format != "" {
+		return f.cfg.Format
+	}
+	return "docx"
+}
+
+// parseFormats parses a comma-separated list of format names or extensions.
+func parseFormats(list string) ([]converter.Format, error) {
+	var formats []converter.Format
+	for _, name := range strings.Split(list, ",") {
+		if name = strings.TrimSpace(name); name == "" {
+			continue
+		}
+		format, ok := converter.LookupFormat(name)
+		if !ok {
+			return nil, usageErrorf("unknown format %q", name)
+		}
+		formats = append(formats, format)
+	}
+	return formats, nil
+}
+
+// runConvert runs the convert subcommand: it converts the HTML files given as
+// arguments, or standard input, each as its own section of one document.
+func runConvert(args []string, stdin io.Reader, stdout, stderr io.Writer) error {
+	fs := flag.NewFlagSet("convert", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() {
+		fmt.Fprintln(stderr, "Usage: html2docx convert [flags] [input.html ...]")
+		fmt.Fprintln(stderr, "\nConverts the input files, or standard input if none or - is given, to one document.")
+		fmt.Fprintln(stderr, "\nFlags:")
+		fs.PrintDefaults()
+	}
+	var flags convertFlags
+	flags.register(fs)
+	if err := fs.Parse(args); err != nil {
+		return flagError(err)
+	}
+
+	opts, page, err := flags.options(fs)
+	if err != nil {
+		return err
+	}
+	outputs, err := flags.outputs(stdout)
+	if err != nil {
+		return err
+	}
+
+	inputs := fs.Args()
+	if len(inputs) == 0 {
+		inputs = []string{"-"}
+	}
+	opts.ResourceDirs = append(inputDirs(inputs), opts.ResourceDirs...)
+	sections := make([]converter.Section, len(inputs))
+	for i, input := range inputs {
+		content, err := readInput(input, stdin)
+		if err != nil {
+			return err
+		}
+		sections[i] = converter.Section{HTML: content, Page: page}
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	job := converter.Job{Sections: sections, Outputs: outputs, Options: opts}
+	res := converter.ConvertBatch(ctx, []converter.Job{job}, 1)[0]
+	for _, out := range res.Outputs {
+		for _, w := range out.Warnings {
+			fmt.Fprintf(stderr, "warning: %s: %s\n", out.Format, w)
+		}
+	}
+	return res.Err
+}
+
+// inputDirs returns the directories of the input files, without duplicates.
+// Relative images are found there first, as a browser opening the inputs
+// would, then in the configured directories.
+func inputDirs(inputs []string) []string {
+	var dirs []string
+	seen := map[string]bool{}
+	for _, input := range inputs {
+		if dir := filepath.Dir(input); input != "-" && !seen[dir] {
+			seen[dir] = true
+			dirs = append(dirs, dir)
+		}
+	}
+	return dirs
+}
+
+// readInput reads an HTML file, or standard input for "-", decoding it to
+// UTF-8.
+func readInput(name string, stdin io.Reader) (string, error) {
+	if name == "-" {
+		return converter.ReadHTML(stdin, "")
+	}
+	f, err := os.Open(name)
+	if err != nil {
+		return "", fmt.Errorf("failed to read input: %w", err)
+	}
+	defer f.Close()
+	return converter.ReadHTML(f, "")
+}
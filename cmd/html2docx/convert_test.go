@@ -0,0 +1,96 @@
+package main
+
+import (
+	"bytes"
+	"image"
+	"image/png"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestConvertStdinToStdout(t *testing.T) {
+	var stdout, stderr strings.Builder
+	code := run([]string{"convert", "-f", "md"}, strings.NewReader("<h1>Title</h1><p>Body</p>"), &stdout, &stderr)
+	if code != 0 {
+		t.Fatalf("exit code %d: %s", code, stderr.String())
+	}
+	if want := "# Title\n\nBody"; stdout.String() != want {
+		t.Errorf("expected %q, got %q", want, stdout.String())
+	}
+}
+
+func TestConvertFiles(t *testing.T) {
+	dir := t.TempDir()
+	first := filepath.Join(dir, "one.html")
+	second := filepath.Join(dir, "two.html")
+	os.WriteFile(first, []byte("<h1>One</h1><iframe></iframe>"), 0644)
+	os.WriteFile(second, []byte("<h1>Two</h1>"), 0644)
+
+	var stdout, stderr strings.Builder
+	out := filepath.Join(dir, "out.pdf")
+	args := []string{"convert", "-f", "pdf,docx,md", "-o", out, "-page-size", "A5 landscape", "-margins", "10mm", "-title", "Both", first, second}
+	if code := run(args, nil, &stdout, &stderr); code != 0 {
+		t.Fatalf("exit code %d: %s", code, stderr.String())
+	}
+	for _, name := range []string{"out.pdf", "out.docx", "out.md"} {
+		if fi, err := os.Stat(filepath.Join(dir, name)); err != nil || fi.Size() == 0 {
+			t.Errorf("expected %s to be written: %v", name, err)
+		}
+	}
+	md, _ := os.ReadFile(filepath.Join(dir, "out.md"))
+	if !strings.Contains(string(md), "# One") || !strings.Contains(string(md), "# Two") {
+		t.Errorf("expected both inputs in the Markdown, got %q", md)
+	}
+	if !strings.Contains(stderr.String(), "warning: pdf: <iframe>") {
+		t.Errorf("expected the iframe to be reported, got %q", stderr.String())
+	}
+}
+
+func TestConvertRelativeImages(t *testing.T) {
+	in, out := t.TempDir(), t.TempDir()
+	var logo bytes.Buffer
+	png.Encode(&logo, image.NewGray(image.Rect(0, 0, 4, 4)))
+	writeTree(t, in, map[string]string{
+		"docs/a.html":    `<img src="img/x.png">`,
+		"docs/img/x.png": logo.String(),
+	})
+
+	var stdout, stderr strings.Builder
+	pdf := filepath.Join(out, "a.pdf")
+	if code := run([]string{"convert", "-o", pdf, filepath.Join(in, "docs", "a.html")}, nil, &stdout, &stderr); code != 0 {
+		t.Fatalf("exit code %d: %s", code, stderr.String())
+	}
+	if stderr.Len() > 0 {
+		t.Errorf("expected the image next to the input to load, got %q", stderr.String())
+	}
+	if data, _ := os.ReadFile(pdf); !bytes.Contains(data, []byte("/Subtype /Image")) {
+		t.Error("expected the image in the document")
+	}
+}
+
+func TestConvertErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+		code int
+		msg  string
+	}{
+		{"no command", nil, 2, "Usage"},
+		{"unknown command", []string{"frobnicate"}, 2, "unknown command"},
+		{"unknown flag", []string{"convert", "-nope"}, 2, "flag provided but not defined"},
+		{"unknown format", []string{"convert", "-f", "odt"}, 2, `unknown format "odt"`},
+		{"bad page size", []string{"convert", "-page-size", "huge"}, 2, "invalid page size"},
+		{"several formats to stdout", []string{"convert", "-f", "pdf,md"}, 2, "need an output file"},
+		{"missing input", []string{"convert", "-f", "md", "missing.html"}, 1, "failed to read input"},
+		{"strict", []string{"convert", "-f", "md", "-strict", "-"}, 1, "iframe"},
+	}
+	for _, tc := range tests {
+		var stdout, stderr strings.Builder
+		code := run(tc.args, strings.NewReader("<iframe></iframe>"), &stdout, &stderr)
+		if code != tc.code || !strings.Contains(stderr.String(), tc.msg) {
+			t.Errorf("%s: exit code %d with %q, want %d with %q", tc.name, code, stderr.String(), tc.code, tc.msg)
+		}
+	}
+}
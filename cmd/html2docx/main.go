@@ -1,54 +1,88 @@
+// Command html2docx converts HTML documents to DOCX, PDF and Markdown, from
+// the command line or as an HTTP server.
 package main
 
 import (
+	"errors"
+	"flag"
 	"fmt"
+	"io"
 	"os"
-
-	"github.com/Achiket123/html2docx/converter"
 )
 
-func main() {
-	htmlContents := []string{}
+const usage = `Usage: html2docx <command> [flags] [arguments]
 
-	content, err := os.ReadFile("test.html")
-	if err != nil {
-		fmt.Printf("Error reading HTML file: %v\n", err)
-		os.Exit(1)
-	}
-	htmlContents = append(htmlContents, string(content))
+Commands:
+  convert   convert HTML files to DOCX, PDF or Markdown
+  batch     convert a directory or glob of HTML files
+  watch     convert HTML files again whenever they change
+  serve     run an HTTP conversion server
 
-	// Convert to Markdown
-	mdFile := "output.md"
-	if err := converter.ConvertHTMLToMarkdown(htmlContents, mdFile); err != nil {
-		fmt.Printf("Error converting to Markdown: %v\n", err)
-		os.Exit(1)
-	}
-	fmt.Printf("Successfully created: %s\n", mdFile)
+Run "html2docx <command> -h" for the flags of a command.
+`
+
+// errUsage is returned for an invalid command line that has already been
+// reported, by the flag package.
+var errUsage = errors.New("invalid usage")
+
+// usageError is an invalid command line.
+type usageError struct{ msg string }
+
+func (e *usageError) Error() string { return e.msg }
 
-	// Create converter and convert HTML to DOCX
-	conv := converter.NewHTMLToDocxConverter()
-	if err := conv.Convert(htmlContents); err != nil {
-		fmt.Printf("Error converting HTML: %v\n", err)
-		os.Exit(1)
+func usageErrorf(format string, args ...interface{}) error {
+	return &usageError{msg: fmt.Sprintf(format, args...)}
+}
+
+// flagError converts an error from parsing flags, which the flag package
+// has already reported, to errUsage.
+func flagError(err error) error {
+	if errors.Is(err, flag.ErrHelp) {
+		return err
 	}
+	return errUsage
+}
 
-	docxFile := "output.docx"
-	if err := conv.SaveToFile(docxFile); err != nil {
-		fmt.Printf("Error saving DOCX: %v\n", err)
-		os.Exit(1)
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
+
+// run runs the command given by args and returns the exit code: 0 on
+// success, 1 if the conversion failed and 2 for an invalid command line.
+func run(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	if len(args) == 0 {
+		fmt.Fprint(stderr, usage)
+		return 2
 	}
-	fmt.Printf("Successfully created: %s\n", docxFile)
 
-	// Convert HTML directly to PDF (no LibreOffice needed)
-	pdfConv := converter.NewHTMLToPDFConverter()
-	if err := pdfConv.Convert(htmlContents); err != nil {
-		fmt.Printf("Error converting to PDF: %v\n", err)
-		os.Exit(1)
+	var err error
+	switch args[0] {
+	case "convert":
+		err = runConvert(args[1:], stdin, stdout, stderr)
+	case "batch":
+		err = runBatch(args[1:], stdout, stderr)
+	case "watch":
+		err = runWatch(args[1:], stdout, stderr)
+	case "serve":
+		err = runServe(args[1:], stderr)
+	case "help", "-h", "-help", "--help":
+		fmt.Fprint(stdout, usage)
+		return 0
+	default:
+		fmt.Fprintf(stderr, "html2docx: unknown command %q\n\n%s", args[0], usage)
+		return 2
 	}
-	pdfFile := "output.pdf"
-	if err := pdfConv.SaveToFile(pdfFile); err != nil {
-		fmt.Printf("Error saving PDF: %v\n", err)
-		os.Exit(1)
+
+	var uerr *usageError
+	switch {
+	case err == nil, errors.Is(err, flag.ErrHelp):
+		return 0
+	case errors.Is(err, errUsage):
+		return 2
+	case errors.As(err, &uerr):
+		fmt.Fprintf(stderr, "html2docx %s: %v\n", args[0], err)
+		return 2
 	}
-	fmt.Printf("Successfully created: %s\n", pdfFile)
+	fmt.Fprintf(stderr, "html2docx %s: %v\n", args[0], err)
+	return 1
 }
@@ -0,0 +1,310 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"os/signal"
+	"path"
+	"runtime"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/Achiket123/html2docx/converter"
+	"golang.org/x/net/html"
+)
+
+// contentTypes are the response content types of the built-in formats.
+var contentTypes = map[string]string{
+	"docx":     "application/vnd.openxmlformats-officedocument.wordprocessingml.document",
+	"pdf":      "application/pdf",
+	"markdown": "text/markdown; charset=utf-8",
+}
+
+// runServe runs the serve subcommand: an HTTP server converting the HTML
+// posted to /convert.
+func runServe(args []string, stderr io.Writer) error {
+	fs := flag.NewFlagSet("serve", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	addr := fs.String("addr", ":8080", "address to listen on")
+	maxBody := fs.Int64("max-body", 10<<20, "maximum request size in bytes")
+	timeout := fs.Duration("timeout", 30*time.Second, "maximum time for one conversion")
+	concurrency := fs.Int("concurrency", runtime.GOMAXPROCS(0), "maximum number of conversions at once")
+	var flags optionFlags
+	fs.StringVar(&flags.config, "config", "", "config `file` (default "+strings.Join(configNames, ", ")+" in the working directory)")
+	if err := fs.Parse(args); err != nil {
+		return flagError(err)
+	}
+
+	opts, err := serverOptions(flags, fs)
+	if err != nil {
+		return err
+	}
+	s := newServer(opts, *maxBody, *timeout, *concurrency)
+	srv := &http.Server{
+		Addr:              *addr,
+		Handler:           s.routes(),
+		ReadHeaderTimeout: 10 * time.Second,
+		ReadTimeout:       *timeout,
+		WriteTimeout:      2 * *timeout,
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	errc := make(chan error, 1)
+	go func() { errc <- srv.ListenAndServe() }()
+	log.Printf("listening on %s", *addr)
+
+	select {
+	case err := <-errc:
+		return err
+	case <-ctx.Done():
+	}
+	shutdown, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+	return srv.Shutdown(shutdown)
+}
+
+// serverOptions loads the config file named by the -config flag parsed by
+// fs, or the one found in the working directory, as the defaults of every
+// conversion. Unlike on the command line, page setup in the posted HTML
+// takes precedence over the config's.
+func serverOptions(flags optionFlags, fs *flag.FlagSet) (converter.Options, error) {
+	opts, page, err := flags.options(fs)
+	if err != nil {
+		return converter.Options{}, err
+	}
+	opts.Page = page
+	return opts, nil
+}
+
+// server converts documents posted over HTTP with one engine per format.
+type server struct {
+	engines map[string]*converter.Engine // keyed by format name
+	maxBody int64
+	timeout time.Duration
+	slots   chan struct{} // limits the number of conversions at once
+}
+
+// Limits the server converts with unless its options set their own, since
+// the HTML it receives is untrusted.
+const (
+	serveMaxDepth = 256
+	serveMaxNodes = 200000
+	serveMaxPages = 1000
+)
+
+// newServer returns a server converting with opts, accepting requests of up
+// to maxBody bytes and running at most concurrency conversions of at most
+// timeout each. Images are only read from the server's filesystem inside
+// opts.ResourceDirs; otherwise they are uploaded with the request or given as
+// data: URIs.
+func newServer(opts converter.Options, maxBody int64, timeout time.Duration, concurrency int) *server {
+	if concurrency <= 0 {
+		concurrency = 1
+	}
+	opts.NoLocalImages = true
+	if opts.Limits.MaxDepth == 0 {
+		opts.Limits.MaxDepth = serveMaxDepth
+	}
+	if opts.Limits.MaxNodes == 0 {
+		opts.Limits.MaxNodes = serveMaxNodes
+	}
+	if opts.Limits.MaxPages == 0 {
+		opts.Limits.MaxPages = serveMaxPages
+	}
+	if opts.Limits.MaxImageBytes == 0 {
+		opts.Limits.MaxImageBytes = maxBody
+	}
+	s := &server{
+		engines: map[string]*converter.Engine{},
+		maxBody: maxBody,
+		timeout: timeout,
+		slots:   make(chan struct{}, concurrency),
+	}
+	for _, f := range converter.Formats() {
+		s.engines[f.Name], _ = converter.NewFormatEngine(f.Name, opts)
+	}
+	return s
+}
+
+func (s *server) routes() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /healthz", s.handleHealth)
+	mux.HandleFunc("POST /convert", s.handleConvert)
+	return mux
+}
+
+func (s *server) handleHealth(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	io.WriteString(w, "ok\n")
+}
+
+// handleConvert converts the HTML in the request body to the format given by
+// the format query parameter and responds with the document. The body is
+// either HTML or a multipart form with the HTML in a part named "html" and
+// the files it references, such as images, in other parts.
+func (s *server) handleConvert(w http.ResponseWriter, r *http.Request) {
+	f, ok := converter.LookupFormat(r.URL.Query().Get("format"))
+	if !ok {
+		http.Error(w, fmt.Sprintf("unknown format %q", r.URL.Query().Get("format")), http.StatusBadRequest)
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, s.maxBody)
+	content, err := readRequestHTML(r)
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			http.Error(w, "request body too large", http.StatusRequestEntityTooLarge)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), s.timeout)
+	defer cancel()
+	select {
+	case s.slots <- struct{}{}:
+		defer func() { <-s.slots }()
+	case <-ctx.Done():
+		http.Error(w, "server busy", http.StatusServiceUnavailable)
+		return
+	}
+
+	conv, err := s.engines[f.Name].Convert(ctx, []string{content})
+	if err != nil {
+		convertError(w, err, convertStatus(err))
+		return
+	}
+	data, err := conv.Bytes()
+	if err != nil {
+		convertError(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	contentType := contentTypes[f.Name]
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", `attachment; filename="document.`+f.Extensions[0]+`"`)
+	w.Header().Set("Content-Length", strconv.Itoa(len(data)))
+	w.Header().Set("X-Conversion-Warnings", strconv.Itoa(len(conv.Warnings())))
+	w.Write(data)
+}
+
+// convertError responds to a failed conversion. Internal errors are logged
+// rather than sent, since they may describe the server.
+func convertError(w http.ResponseWriter, err error, status int) {
+	if status == http.StatusInternalServerError {
+		log.Printf("conversion failed: %v", err)
+		http.Error(w, "conversion failed", status)
+		return
+	}
+	http.Error(w, err.Error(), status)
+}
+
+// convertStatus returns the HTTP status for a failed conversion.
+func convertStatus(err error) int {
+	var warning *converter.WarningError
+	switch {
+	case errors.Is(err, context.DeadlineExceeded):
+		return http.StatusServiceUnavailable
+	case errors.Is(err, converter.ErrLimitExceeded), errors.As(err, &warning):
+		return http.StatusUnprocessableEntity
+	}
+	return http.StatusInternalServerError
+}
+
+// readRequestHTML returns the HTML posted in a request, with the images
+// uploaded alongside a multipart form inlined as data: URIs.
+func readRequestHTML(r *http.Request) (string, error) {
+	mediaType, params, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if mediaType != "multipart/form-data" {
+		return converter.ReadHTML(r.Body, r.Header.Get("Content-Type"))
+	}
+
+	mr := multipart.NewReader(r.Body, params["boundary"])
+	var content string
+	found := false
+	assets := map[string][]byte{}
+	for {
+		part, err := mr.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return "", fmt.Errorf("failed to read multipart body: %w", err)
+		}
+		if part.FormName() == "html" {
+			if content, err = converter.ReadHTML(part, part.Header.Get("Content-Type")); err != nil {
+				return "", err
+			}
+			found = true
+			continue
+		}
+		if name := part.FileName(); name != "" {
+			data, err := io.ReadAll(part)
+			if err != nil {
+				return "", fmt.Errorf("failed to read %s: %w", name, err)
+			}
+			assets[name] = data
+		}
+	}
+	if !found {
+		return "", errors.New(`missing "html" part`)
+	}
+	return inlineAssets(content, assets)
+}
+
+// inlineAssets replaces the src of images that name an uploaded file with a
+// data: URI holding the file.
+func inlineAssets(content string, assets map[string][]byte) (string, error) {
+	if len(assets) == 0 {
+		return content, nil
+	}
+	root, err := html.Parse(strings.NewReader(content))
+	if err != nil {
+		return "", fmt.Errorf("failed to parse HTML: %w", err)
+	}
+	var walk func(*html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.ElementNode && n.Data == "img" {
+			for i, a := range n.Attr {
+				if a.Key != "src" {
+					continue
+				}
+				data, ok := assets[a.Val]
+				if !ok {
+					data, ok = assets[path.Base(a.Val)]
+				}
+				if ok {
+					n.Attr[i].Val = "data:" + http.DetectContentType(data) + ";base64," + base64.StdEncoding.EncodeToString(data)
+				}
+			}
+		}
+		for ch := n.FirstChild; ch != nil; ch = ch.NextSibling {
+			walk(ch)
+		}
+	}
+	walk(root)
+
+	var buf bytes.Buffer
+	if err := html.Render(&buf, root); err != nil {
+		return "", fmt.Errorf("failed to render HTML: %w", err)
+	}
+	return buf.String(), nil
+}
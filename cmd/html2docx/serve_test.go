@@ -0,0 +1,206 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"image"
+	"image/png"
+	"io"
+	"log"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Achiket123/html2docx/converter"
+)
+
+func newTestServer() http.Handler {
+	return newServer(converter.Options{}, 1<<20, 5*time.Second, 2).routes()
+}
+
+func TestServeHealth(t *testing.T) {
+	rec := httptest.NewRecorder()
+	newTestServer().ServeHTTP(rec, httptest.NewRequest("GET", "/healthz", nil))
+	if rec.Code != http.StatusOK {
+		t.Errorf("expected 200, got %d", rec.Code)
+	}
+}
+
+func TestServeConvert(t *testing.T) {
+	tests := []struct {
+		format      string
+		contentType string
+	}{
+		{"md", "text/markdown; charset=utf-8"},
+		{"docx", contentTypes["docx"]},
+		{"pdf", "application/pdf"},
+	}
+	for _, tc := range tests {
+		req := httptest.NewRequest("POST", "/convert?format="+tc.format, strings.NewReader("<h1>Hello</h1>"))
+		req.Header.Set("Content-Type", "text/html; charset=utf-8")
+		rec := httptest.NewRecorder()
+		newTestServer().ServeHTTP(rec, req)
+
+		if rec.Code != http.StatusOK {
+			t.Fatalf("%s: expected 200, got %d: %s", tc.format, rec.Code, rec.Body)
+		}
+		if got := rec.Header().Get("Content-Type"); got != tc.contentType {
+			t.Errorf("%s: Content-Type = %q, want %q", tc.format, got, tc.contentType)
+		}
+		if rec.Body.Len() == 0 {
+			t.Errorf("%s: expected a document", tc.format)
+		}
+	}
+}
+
+func TestServeConvertMultipart(t *testing.T) {
+	var img bytes.Buffer
+	png.Encode(&img, image.NewRGBA(image.Rect(0, 0, 4, 4)))
+
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	mw.WriteField("html", `<p>Logo:</p><img src="images/logo.png">`)
+	fw, _ := mw.CreateFormFile("logo", "logo.png")
+	fw.Write(img.Bytes())
+	mw.Close()
+
+	req := httptest.NewRequest("POST", "/convert?format=pdf", &body)
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	rec := httptest.NewRecorder()
+	newTestServer().ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body)
+	}
+	if got := rec.Header().Get("X-Conversion-Warnings"); got != "0" {
+		t.Errorf("expected the uploaded image to load, got %s warnings", got)
+	}
+}
+
+func TestServeConvertErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		url    string
+		body   string
+		status int
+	}{
+		{"unknown format", "/convert?format=odt", "<p>x</p>", http.StatusBadRequest},
+		{"too large", "/convert?format=md", strings.Repeat("x", 2<<20), http.StatusRequestEntityTooLarge},
+		{"wrong method", "/convert?format=md", "", http.StatusMethodNotAllowed},
+	}
+	for _, tc := range tests {
+		method := "POST"
+		if tc.body == "" {
+			method = "GET"
+		}
+		rec := httptest.NewRecorder()
+		newTestServer().ServeHTTP(rec, httptest.NewRequest(method, tc.url, strings.NewReader(tc.body)))
+		if rec.Code != tc.status {
+			t.Errorf("%s: expected %d, got %d", tc.name, tc.status, rec.Code)
+		}
+	}
+}
+
+func TestServeConvertLocalImage(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "secret.png")
+	var img bytes.Buffer
+	png.Encode(&img, image.NewGray(image.Rect(0, 0, 4, 4)))
+	os.WriteFile(path, img.Bytes(), 0644)
+
+	req := httptest.NewRequest("POST", "/convert?format=pdf", strings.NewReader(`<img src="`+path+`">`))
+	rec := httptest.NewRecorder()
+	newTestServer().ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body)
+	}
+	if got := rec.Header().Get("X-Conversion-Warnings"); got != "1" {
+		t.Errorf("expected the image on the server's disk not to load, got %s warnings", got)
+	}
+	if strings.Contains(rec.Body.String(), "/Subtype /Image") {
+		t.Error("expected no image in the document")
+	}
+}
+
+func TestServeDefaultLimits(t *testing.T) {
+	deep := strings.Repeat("<div>", serveMaxDepth+10) + "x"
+	req := httptest.NewRequest("POST", "/convert?format=md", strings.NewReader(deep))
+	rec := httptest.NewRecorder()
+	newTestServer().ServeHTTP(rec, req)
+	if rec.Code != http.StatusUnprocessableEntity {
+		t.Errorf("expected the default depth limit to reject the document with 422, got %d", rec.Code)
+	}
+}
+
+func TestConvertError(t *testing.T) {
+	log.SetOutput(io.Discard)
+	defer log.SetOutput(os.Stderr)
+
+	rec := httptest.NewRecorder()
+	convertError(rec, errors.New("open /srv/data/x: permission denied"), http.StatusInternalServerError)
+	if rec.Code != http.StatusInternalServerError || strings.Contains(rec.Body.String(), "/srv/data") {
+		t.Errorf("expected a generic 500, got %d %q", rec.Code, rec.Body)
+	}
+
+	rec = httptest.NewRecorder()
+	convertError(rec, errors.New("depth limit of 256 exceeded"), http.StatusUnprocessableEntity)
+	if !strings.Contains(rec.Body.String(), "depth limit") {
+		t.Errorf("expected the reason for a rejected document, got %q", rec.Body)
+	}
+}
+
+func TestServeConfig(t *testing.T) {
+	dir := t.TempDir()
+	t.Chdir(dir)
+	var logo bytes.Buffer
+	png.Encode(&logo, image.NewGray(image.Rect(0, 0, 4, 4)))
+	writeTree(t, dir, map[string]string{
+		"serve.yaml": `
+page: {size: A5}
+classes:
+  note: {bold: true}
+resources: [assets]
+`,
+		"assets/logo.png": logo.String(),
+		"secret.png":      logo.String(),
+	})
+
+	fs := flag.NewFlagSet("serve", flag.ContinueOnError)
+	flags := optionFlags{config: "serve.yaml"}
+	opts, err := serverOptions(flags, fs)
+	if err != nil {
+		t.Fatalf("serverOptions failed: %v", err)
+	}
+	srv := newServer(opts, 1<<20, 5*time.Second, 2).routes()
+
+	post := func(format, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", "/convert?format="+format, strings.NewReader(body))
+		req.Header.Set("Content-Type", "text/html; charset=utf-8")
+		rec := httptest.NewRecorder()
+		srv.ServeHTTP(rec, req)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("%s: expected 200, got %d: %s", format, rec.Code, rec.Body)
+		}
+		return rec
+	}
+	if md := post("md", `<p>A <span class="note">note</span></p>`).Body.String(); !strings.Contains(md, "A **note**") {
+		t.Errorf("expected the class style of the config, got %q", md)
+	}
+	rec := post("pdf", `<img src="logo.png"><img src="../secret.png">`)
+	if !bytes.Contains(rec.Body.Bytes(), []byte("/MediaBox [0 0 419.53 595.28]")) {
+		t.Error("expected the A5 page size of the config")
+	}
+	if rec.Header().Get("X-Conversion-Warnings") != "1" || bytes.Count(rec.Body.Bytes(), []byte("/Subtype /Image")) != 1 {
+		t.Errorf("expected only the image in the resource directory to load, got %s warnings", rec.Header().Get("X-Conversion-Warnings"))
+	}
+
+	if _, err := serverOptions(optionFlags{config: "missing.yaml"}, fs); err == nil {
+		t.Error("expected an error for a missing config file")
+	}
+}
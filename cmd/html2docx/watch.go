@@ -0,0 +1,260 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/Achiket123/html2docx/converter"
+	"github.com/fsnotify/fsnotify"
+	"golang.org/x/net/html"
+)
+
+// runWatch runs the watch subcommand: it converts the input files, then
+// converts them again whenever they or the local images they reference
+// change, until interrupted.
+func runWatch(args []string, stdout, stderr io.Writer) error {
+	fs := flag.NewFlagSet("watch", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() {
+		fmt.Fprintln(stderr, "Usage: html2docx watch [flags] input.html ...")
+		fmt.Fprintln(stderr, "\nConverts the input files to one document and converts them again whenever they, or the images they reference, change.")
+		fmt.Fprintln(stderr, "\nFlags:")
+		fs.PrintDefaults()
+	}
+	var flags convertFlags
+	flags.register(fs)
+	debounce := fs.Duration("debounce", 200*time.Millisecond, "time to wait for further changes before converting")
+	if err := fs.Parse(args); err != nil {
+		return flagError(err)
+	}
+	if fs.NArg() == 0 {
+		return usageErrorf("missing input file")
+	}
+	for _, input := range fs.Args() {
+		if input == "-" {
+			return usageErrorf("cannot watch standard input")
+		}
+	}
+
+	opts, page, err := flags.options(fs)
+	if err != nil {
+		return err
+	}
+	if flags.output == "" || flags.output == "-" {
+		// Write next to the first input, named after it.
+		if flags.formats == "" {
+			flags.formats = flags.defaultFormat()
+		}
+		first := fs.Arg(0)
+		flags.output = strings.TrimSuffix(first, filepath.Ext(first))
+		if f, ok := converter.LookupFormat(flags.formats); ok {
+			flags.output += "." + f.Extensions[0]
+		}
+	}
+	outputs, err := flags.outputs(nil)
+	if err != nil {
+		return err
+	}
+
+	opts.ResourceDirs = append(inputDirs(fs.Args()), opts.ResourceDirs...)
+
+	w := &watcher{
+		inputs:   fs.Args(),
+		outputs:  outputs,
+		options:  opts,
+		page:     page,
+		debounce: *debounce,
+		stdout:   stdout,
+		stderr:   stderr,
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	return w.run(ctx)
+}
+
+// watcher converts its inputs each time a file they depend on changes.
+type watcher struct {
+	inputs   []string
+	outputs  []converter.Output
+	options  converter.Options
+	page     converter.PageSetup
+	debounce time.Duration // quiet time after a change before converting
+	stdout   io.Writer
+	stderr   io.Writer
+}
+
+// run converts the inputs and watches them until ctx is done. A failed
+// conversion is reported and watching goes on.
+func (w *watcher) run(ctx context.Context) error {
+	fsw, err := fsnotify.NewWatcher()
+	if err != nil {
+		return fmt.Errorf("failed to start watching: %w", err)
+	}
+	defer fsw.Close()
+
+	// Directories are watched rather than files, since many editors save by
+	// replacing the file, which ends a watch on the file itself.
+	watched := map[string]bool{} // watched files
+	dirs := map[string]bool{}
+	update := func(files []string) {
+		watched = map[string]bool{}
+		for _, f := range files {
+			watched[f] = true
+			dir := filepath.Dir(f)
+			if dirs[dir] {
+				continue
+			}
+			if err := fsw.Add(dir); err != nil {
+				fmt.Fprintf(w.stderr, "warning: cannot watch %s: %v\n", dir, err)
+				continue
+			}
+			dirs[dir] = true
+		}
+	}
+	update(w.convert(ctx))
+	fmt.Fprintf(w.stdout, "watching %d files, press Ctrl+C to stop\n", len(watched))
+
+	timer := time.NewTimer(w.debounce)
+	timer.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case ev, ok := <-fsw.Events:
+			if !ok {
+				return nil
+			}
+			if ev.Op == fsnotify.Chmod || !watched[filepath.Clean(ev.Name)] {
+				continue
+			}
+			// Wait for the burst of events of a save, or of several
+			// quick saves, to end before converting.
+			timer.Reset(w.debounce)
+		case err, ok := <-fsw.Errors:
+			if !ok {
+				return nil
+			}
+			fmt.Fprintf(w.stderr, "warning: watch: %v\n", err)
+		case <-timer.C:
+			update(w.convert(ctx))
+		}
+	}
+}
+
+// convert converts the inputs, reports the outcome and returns the absolute
+// paths of the files to watch: the inputs and the local images they
+// reference.
+func (w *watcher) convert(ctx context.Context) []string {
+	var files []string
+	sections := make([]converter.Section, 0, len(w.inputs))
+	var readErr error
+	for _, input := range w.inputs {
+		if abs, err := filepath.Abs(input); err == nil {
+			files = append(files, abs)
+		}
+		content, err := readInput(input, nil)
+		if err != nil {
+			if readErr == nil {
+				readErr = err
+			}
+			continue
+		}
+		dirs := append([]string{filepath.Dir(input)}, w.options.ResourceDirs...)
+		files = append(files, referencedFiles(content, dirs)...)
+		sections = append(sections, converter.Section{HTML: content, Page: w.page})
+	}
+
+	stamp := time.Now().Format("15:04:05")
+	if readErr != nil {
+		fmt.Fprintf(w.stderr, "%s conversion failed: %v\n", stamp, readErr)
+		return files
+	}
+	job := converter.Job{Sections: sections, Outputs: w.outputs, Options: w.options}
+	res := converter.ConvertBatch(ctx, []converter.Job{job}, 1)[0]
+	warnings := 0
+	for _, out := range res.Outputs {
+		for _, warning := range out.Warnings {
+			fmt.Fprintf(w.stderr, "warning: %s: %s\n", out.Format, warning)
+			warnings++
+		}
+	}
+	if res.Err != nil {
+		fmt.Fprintf(w.stderr, "%s conversion failed: %v\n", stamp, res.Err)
+		return files
+	}
+	paths := make([]string, len(w.outputs))
+	for i, out := range w.outputs {
+		paths[i] = out.Path
+	}
+	fmt.Fprintf(w.stdout, "%s converted %s (%d warnings)\n", stamp, strings.Join(paths, ", "), warnings)
+	return files
+}
+
+// referencedFiles returns the absolute paths of the local images an HTML
+// document references. Relative sources are looked up in dirs in order, as
+// the converter looks them up in Options.ResourceDirs; a source found in none
+// is watched in the first, so that adding it converts again.
+func referencedFiles(content string, dirs []string) []string {
+	root, err := html.Parse(strings.NewReader(converter.UnescapeUnicodeHTML(content)))
+	if err != nil {
+		return nil
+	}
+	var files []string
+	seen := map[string]bool{}
+	var walk func(*html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.ElementNode && n.Data == "img" {
+			if path, ok := localPath(converter.GetAttrValue(n.Attr, "src"), dirs); ok && !seen[path] {
+				seen[path] = true
+				files = append(files, path)
+			}
+		}
+		for ch := n.FirstChild; ch != nil; ch = ch.NextSibling {
+			walk(ch)
+		}
+	}
+	walk(root)
+	return files
+}
+
+// localPath returns the absolute path of the file an image source refers to,
+// looking a relative source up in dirs, and false for remote URLs, data: URIs
+// and fragments.
+func localPath(ref string, dirs []string) (string, bool) {
+	if ref == "" || strings.HasPrefix(ref, "#") {
+		return "", false
+	}
+	if u, err := url.Parse(ref); err == nil && u.Scheme != "" && len(u.Scheme) > 1 {
+		if u.Scheme != "file" {
+			return "", false
+		}
+		ref = u.Path
+	}
+	if i := strings.IndexAny(ref, "?#"); i >= 0 {
+		ref = ref[:i]
+	}
+	path := filepath.FromSlash(ref)
+	if !filepath.IsAbs(path) && len(dirs) > 0 {
+		path = filepath.Join(dirs[0], path)
+		for _, dir := range dirs {
+			candidate := filepath.Join(dir, filepath.FromSlash(ref))
+			if fi, err := os.Stat(candidate); err == nil && !fi.IsDir() {
+				path = candidate
+				break
+			}
+		}
+	}
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return "", false
+	}
+	return abs, true
+}
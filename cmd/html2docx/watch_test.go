@@ -0,0 +1,134 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Achiket123/html2docx/converter"
+)
+
+func TestReferencedFiles(t *testing.T) {
+	dir := t.TempDir()
+	t.Chdir(t.TempDir())
+	writeTree(t, dir, map[string]string{
+		"doc/both.png":    "png",
+		"assets/res.png":  "png",
+		"assets/both.png": "png",
+		"doc/site.css":    `body { background: url(bg.png) }`,
+	})
+
+	content := `<html><head>
+		<link rel="stylesheet" href="site.css">
+		<style>h1 { background: url(banner.png) }</style>
+	</head><body>
+		<img src="logo.png?v=2"><img src="https://example.com/a.png"><img src="data:image/png;base64,AA==">
+		<img src="res.png"><img src="both.png"><img src="` + filepath.Join(dir, "abs.png") + `">
+		\u003cimg src=\"escaped.png\"\u003e
+		<a href="other.html">not watched</a>
+	</body></html>`
+
+	var got []string
+	for _, f := range referencedFiles(content, []string{filepath.Join(dir, "doc"), filepath.Join(dir, "assets")}) {
+		rel, _ := filepath.Rel(dir, f)
+		got = append(got, filepath.ToSlash(rel))
+	}
+	sort.Strings(got)
+	want := []string{"abs.png", "assets/res.png", "doc/both.png", "doc/escaped.png", "doc/logo.png"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+}
+
+// syncBuilder is a strings.Builder safe for concurrent use.
+type syncBuilder struct {
+	mu sync.Mutex
+	b  strings.Builder
+}
+
+func (s *syncBuilder) Write(p []byte) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.b.Write(p)
+}
+
+func (s *syncBuilder) String() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.b.String()
+}
+
+func TestWatchReconverts(t *testing.T) {
+	dir := t.TempDir()
+	t.Chdir(dir)
+	writeTree(t, dir, map[string]string{
+		"docs/doc.html": `<img src="logo.png"><h1>First</h1>`,
+		"docs/logo.png": "png",
+	})
+
+	var stdout, stderr syncBuilder
+	w := &watcher{
+		inputs:   []string{filepath.Join("docs", "doc.html")},
+		outputs:  []converter.Output{{Format: "markdown", Path: "doc.md"}},
+		options:  converter.Options{ResourceDirs: []string{"docs"}},
+		debounce: 20 * time.Millisecond,
+		stdout:   &stdout,
+		stderr:   &stderr,
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- w.run(ctx) }()
+
+	waitFor := func(what string, ok func() bool) {
+		t.Helper()
+		for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+			if ok() {
+				return
+			}
+		}
+		t.Fatalf("timed out waiting for %s; stdout %q, stderr %q", what, stdout.String(), stderr.String())
+	}
+	conversions := func() int { return strings.Count(stdout.String(), "converted doc.md") }
+	waitFor("the first conversion", func() bool { return strings.Contains(stdout.String(), "watching 2 files") })
+
+	os.WriteFile(filepath.Join("docs", "doc.html"), []byte(`<img src="logo.png"><h1>Second</h1><iframe></iframe>`), 0644)
+	waitFor("the edit to be converted", func() bool {
+		return strings.Contains(stderr.String(), "warning: markdown: <iframe>")
+	})
+	if md, _ := os.ReadFile("doc.md"); !strings.Contains(string(md), "# Second") {
+		t.Errorf("expected the edit in the output, got %q", md)
+	}
+
+	before := conversions()
+	os.WriteFile(filepath.Join("docs", "logo.png"), []byte("new png"), 0644)
+	waitFor("the image change to be converted", func() bool { return conversions() > before })
+
+	cancel()
+	if err := <-done; err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestWatchErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+		msg  string
+	}{
+		{"no input", []string{"watch"}, "missing input file"},
+		{"stdin", []string{"watch", "-"}, "cannot watch standard input"},
+		{"unknown format", []string{"watch", "-f", "odt", "doc.html"}, `unknown format "odt"`},
+	}
+	for _, tc := range tests {
+		var stdout, stderr strings.Builder
+		if code := run(tc.args, nil, &stdout, &stderr); code != 2 || !strings.Contains(stderr.String(), tc.msg) {
+			t.Errorf("%s: exit code %d with %q, want 2 with %q", tc.name, code, stderr.String(), tc.msg)
+		}
+	}
+}
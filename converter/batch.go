@@ -0,0 +1,151 @@
+package converter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"runtime"
+	"sync"
+)
+
+// Job is one input of a batch conversion, converted to one or more outputs.
+type Job struct {
+	Sections []Section // HTML inputs, each its own section, see ConvertSections
+	Outputs  []Output  // formats to convert to and where to write them
+	Options  Options   // options for every output of the job
+}
+
+// Output is one format a Job is converted to and where it is written.
+type Output struct {
+	Format string    // format name or file extension, see LookupFormat
+	Path   string    // file to write the document to
+	Writer io.Writer // written to instead when Path is empty
+}
+
+// Result is the outcome of one Job.
+type Result struct {
+	Job     int            // index of the job in the batch
+	Outputs []OutputResult // one per Job.Outputs, in the same order
+	Err     error          // parsing the input or any output failed, see Outputs
+}
+
+// OutputResult is the outcome of one Output of a Job.
+type OutputResult struct {
+	Format   string
+	Path     string
+	Written  int64     // bytes written
+	Warnings []Warning // unsupported content, see Converter.Warnings
+	Err      error
+}
+
+// documentConverter is implemented by the built-in converters, which can
+// render documents already built from HTML. A batch builds each input once
+// for all its outputs.
+type documentConverter interface {
+	convertDocuments(ctx context.Context, docs []*Document) error
+}
+
+// ConvertBatch converts jobs concurrently on at most workers goroutines, or
+// GOMAXPROCS when workers is zero or less. The HTML of a job is parsed once
+// for all its outputs. It returns one result per job in the order of jobs;
+// jobs not started before ctx is done fail with the context's error.
+//
+// Outputs sharing a Writer must not be converted concurrently, so give them
+// to the same job or use a synchronized writer.
+func ConvertBatch(ctx context.Context, jobs []Job, workers int) []Result {
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+	results := make([]Result, len(jobs))
+	indexes := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers && w < len(jobs); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				results[i] = convertJob(ctx, i, jobs[i])
+			}
+		}()
+	}
+
+	for i := range jobs {
+		if ctx.Err() != nil {
+			results[i] = Result{Job: i, Err: fmt.Errorf("conversion stopped: %w", ctx.Err())}
+			continue
+		}
+		select {
+		case indexes <- i:
+		case <-ctx.Done():
+			results[i] = Result{Job: i, Err: fmt.Errorf("conversion stopped: %w", ctx.Err())}
+		}
+	}
+	close(indexes)
+	wg.Wait()
+	return results
+}
+
+// convertJob parses the input of a job and converts it to each output.
+func convertJob(ctx context.Context, i int, job Job) Result {
+	res := Result{Job: i, Outputs: make([]OutputResult, len(job.Outputs))}
+	docs, err := parseSections(ctx, job.Sections, job.Options.Limits, job.Options.ClassStyles)
+	if err != nil {
+		res.Err = err
+		return res
+	}
+	var errs []error
+	for j, out := range job.Outputs {
+		res.Outputs[j] = convertOutput(ctx, job, docs, out)
+		if err := res.Outputs[j].Err; err != nil {
+			errs = append(errs, err)
+		}
+	}
+	res.Err = errors.Join(errs...)
+	return res
+}
+
+// convertOutput converts a parsed job to one output format and writes it.
+func convertOutput(ctx context.Context, job Job, docs []*Document, out Output) OutputResult {
+	res := OutputResult{Format: out.Format, Path: out.Path}
+	conv, err := NewConverter(out.Format, job.Options)
+	if err != nil {
+		res.Err = err
+		return res
+	}
+	if out.Path == "" && out.Writer == nil {
+		res.Err = fmt.Errorf("no destination for %s output", out.Format)
+		return res
+	}
+
+	if dc, ok := conv.(documentConverter); ok {
+		err = dc.convertDocuments(ctx, docs)
+	} else {
+		err = conv.ConvertSectionsContext(ctx, job.Sections)
+	}
+	res.Warnings = conv.Warnings()
+	if err != nil {
+		res.Err = fmt.Errorf("failed to convert to %s: %w", out.Format, err)
+		return res
+	}
+
+	w := out.Writer
+	if out.Path != "" {
+		f, err := os.Create(out.Path)
+		if err != nil {
+			res.Err = fmt.Errorf("failed to create output file: %w", err)
+			return res
+		}
+		defer func() {
+			if err := f.Close(); err != nil && res.Err == nil {
+				res.Err = fmt.Errorf("failed to close output file: %w", err)
+			}
+		}()
+		w = f
+	}
+	if res.Written, err = conv.WriteTo(w); err != nil {
+		res.Err = fmt.Errorf("failed to write %s output: %w", out.Format, err)
+	}
+	return res
+}
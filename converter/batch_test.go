@@ -0,0 +1,77 @@
+package converter
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestConvertBatch(t *testing.T) {
+	dir := t.TempDir()
+	var md strings.Builder
+	jobs := []Job{
+		{
+			Sections: HTMLSections([]string{`<h1>Report</h1><p>Shared input</p>`}),
+			Outputs: []Output{
+				{Format: "docx", Path: filepath.Join(dir, "report.docx")},
+				{Format: "pdf", Path: filepath.Join(dir, "report.pdf")},
+				{Format: "md", Writer: &md},
+			},
+		},
+		{
+			Sections: HTMLSections([]string{`<p>Unknown format</p>`}),
+			Outputs:  []Output{{Format: "odt", Path: filepath.Join(dir, "x.odt")}},
+		},
+		{
+			Sections: HTMLSections([]string{`<div><div><div><p>Too deep</p></div></div></div>`}),
+			Outputs:  []Output{{Format: "pdf", Path: filepath.Join(dir, "deep.pdf")}},
+			Options:  Options{Limits: Limits{MaxDepth: 3}},
+		},
+	}
+
+	results := ConvertBatch(context.Background(), jobs, 2)
+	if len(results) != len(jobs) {
+		t.Fatalf("expected %d results, got %d", len(jobs), len(results))
+	}
+
+	ok := results[0]
+	if ok.Err != nil {
+		t.Fatalf("job 0 failed: %v", ok.Err)
+	}
+	for i, out := range ok.Outputs {
+		if out.Err != nil || out.Written == 0 {
+			t.Errorf("output %d: written %d, err %v", i, out.Written, out.Err)
+		}
+	}
+	for _, name := range []string{"report.docx", "report.pdf"} {
+		if fi, err := os.Stat(filepath.Join(dir, name)); err != nil || fi.Size() == 0 {
+			t.Errorf("expected %s to be written: %v", name, err)
+		}
+	}
+	if !strings.Contains(md.String(), "# Report") {
+		t.Errorf("expected Markdown output, got %q", md.String())
+	}
+
+	if results[1].Err == nil || results[1].Outputs[0].Err == nil {
+		t.Error("expected an unknown format to fail its output")
+	}
+	if !errors.Is(results[2].Err, ErrLimitExceeded) {
+		t.Errorf("expected a limit error, got %v", results[2].Err)
+	}
+}
+
+func TestConvertBatchCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	jobs := []Job{{
+		Sections: HTMLSections([]string{`<p>Never converted</p>`}),
+		Outputs:  []Output{{Format: "md", Writer: &strings.Builder{}}},
+	}}
+	results := ConvertBatch(ctx, jobs, 1)
+	if !errors.Is(results[0].Err, context.Canceled) {
+		t.Errorf("expected a canceled job, got %v", results[0].Err)
+	}
+}
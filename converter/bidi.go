@@ -0,0 +1,158 @@
+package converter
+
+import (
+	"golang.org/x/text/unicode/bidi"
+)
+
+// lrm is the left-to-right mark, used to force a left-to-right paragraph
+// direction when resolving bidi levels.
+const lrm = "‎"
+
+// isRTLRune reports whether r is a strong right-to-left character, such as
+// Hebrew or Arabic letters.
+func isRTLRune(r rune) bool {
+	props, _ := bidi.LookupRune(r)
+	class := props.Class()
+	return class == bidi.R || class == bidi.AL
+}
+
+// HasRTL reports whether text contains any right-to-left characters.
+func HasRTL(text string) bool {
+	for _, r := range text {
+		if isRTLRune(r) {
+			return true
+		}
+	}
+	return false
+}
+
+// IsRTLText reports whether text reads right to left, judged by its first
+// strong directional character as in the Unicode bidi algorithm.
+func IsRTLText(text string) bool {
+	for _, r := range text {
+		props, _ := bidi.LookupRune(r)
+		switch props.Class() {
+		case bidi.R, bidi.AL:
+			return true
+		case bidi.L:
+			return false
+		}
+	}
+	return false
+}
+
+// bidiLevels returns the embedding level of each rune of a line of text in a
+// paragraph of the given direction. Text running against the paragraph
+// direction is one level deeper, and numbers inside right-to-left text in a
+// left-to-right paragraph two levels deeper.
+func bidiLevels(text string, rtl bool) []int {
+	runes := []rune(text)
+	levels := make([]int, len(runes))
+	if len(runes) == 0 {
+		return levels
+	}
+
+	var p bidi.Paragraph
+	offset := 0
+	if rtl {
+		p.SetString(text, bidi.DefaultDirection(bidi.RightToLeft))
+	} else {
+		p.SetString(lrm+text, bidi.DefaultDirection(bidi.LeftToRight))
+		offset = 1
+	}
+	order, err := p.Order()
+	if err != nil {
+		return levels
+	}
+
+	prevRTL := false
+	for i := 0; i < order.NumRuns(); i++ {
+		run := order.Run(i)
+		start, end := run.Pos()
+		isRTL := run.Direction() == bidi.RightToLeft
+		level := 0
+		switch {
+		case rtl && isRTL:
+			level = 1
+		case rtl:
+			level = 2
+		case isRTL:
+			level = 1
+		case prevRTL && !hasStrongLTR(run.String()):
+			level = 2
+		}
+		for j := start - offset; j <= end-offset; j++ {
+			if j >= 0 && j < len(levels) {
+				levels[j] = level
+			}
+		}
+		prevRTL = isRTL || level == 2
+	}
+	return levels
+}
+
+// hasStrongLTR reports whether text contains a strong left-to-right character.
+func hasStrongLTR(text string) bool {
+	for _, r := range text {
+		if props, _ := bidi.LookupRune(r); props.Class() == bidi.L {
+			return true
+		}
+	}
+	return false
+}
+
+// visualOrder returns the indexes of items in display order given their
+// embedding levels, reversing every run at or above each odd level from the
+// highest down (rule L2 of the Unicode bidi algorithm).
+func visualOrder(levels []int) []int {
+	order := make([]int, len(levels))
+	maxLevel, minOdd := 0, -1
+	for i, level := range levels {
+		order[i] = i
+		if level > maxLevel {
+			maxLevel = level
+		}
+		if level%2 == 1 && (minOdd < 0 || level < minOdd) {
+			minOdd = level
+		}
+	}
+	if minOdd < 0 {
+		return order
+	}
+	for level := maxLevel; level >= minOdd; level-- {
+		for i := 0; i < len(order); {
+			if levels[order[i]] < level {
+				i++
+				continue
+			}
+			j := i
+			for j < len(order) && levels[order[j]] >= level {
+				j++
+			}
+			for a, b := i, j-1; a < b; a, b = a+1, b-1 {
+				order[a], order[b] = order[b], order[a]
+			}
+			i = j
+		}
+	}
+	return order
+}
+
+// visualText returns a single line of text in display order, for renderers
+// that draw characters strictly left to right.
+func visualText(text string, rtl bool) string {
+	if !rtl && !HasRTL(text) {
+		return text
+	}
+	runes := []rune(text)
+	levels := bidiLevels(text, rtl)
+	out := make([]rune, 0, len(runes))
+	for _, i := range visualOrder(levels) {
+		if levels[i]%2 == 1 {
+			out = append(out, []rune(bidi.ReverseString(string(runes[i])))...)
+		} else {
+			out = append(out, runes[i])
+		}
+	}
+	return string(out)
+}
@@ -0,0 +1,43 @@
+package converter
+
+import "testing"
+
+func TestVisualText(t *testing.T) {
+	tests := []struct {
+		text     string
+		rtl      bool
+		expected string
+	}{
+		{"plain text", false, "plain text"},
+		{"abc אבג def", false, "abc גבא def"},
+		{"אבג דהו", true, "והד גבא"},
+		{"אבג 123 דהו", false, "והד 123 גבא"},
+		{"אבג def", true, "def גבא"},
+		{"(אבג)", true, "(גבא)"},
+	}
+
+	for _, tc := range tests {
+		if got := visualText(tc.text, tc.rtl); got != tc.expected {
+			t.Errorf("visualText(%q, %v) = %q, want %q", tc.text, tc.rtl, got, tc.expected)
+		}
+	}
+}
+
+func TestIsRTLText(t *testing.T) {
+	tests := []struct {
+		text     string
+		expected bool
+	}{
+		{"Hello", false},
+		{"123 שלום world", true},
+		{"مرحبا", true},
+		{"Hello مرحبا", false},
+		{"123", false},
+	}
+
+	for _, tc := range tests {
+		if got := IsRTLText(tc.text); got != tc.expected {
+			t.Errorf("IsRTLText(%q) = %v, want %v", tc.text, got, tc.expected)
+		}
+	}
+}
@@ -0,0 +1,137 @@
+package converter
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Converter is implemented by the DOCX, PDF and Markdown converters, so the
+// output format can be chosen at runtime.
+type Converter interface {
+	// ConvertSections converts each HTML input as its own section. Formats
+	// without pages ignore the page setup.
+	ConvertSections(sections []Section) error
+	// ConvertSectionsContext is like ConvertSections but stops with an error
+	// once ctx is done or a limit in Options.Limits is exceeded.
+	ConvertSectionsContext(ctx context.Context, sections []Section) error
+	// WriteTo writes the converted document to w.
+	WriteTo(w io.Writer) (int64, error)
+	// Bytes returns the converted document.
+	Bytes() ([]byte, error)
+	// Warnings returns the warnings about unsupported content so far.
+	Warnings() []Warning
+	// SaveToFile writes the converted document to a file.
+	SaveToFile(filename string) error
+}
+
+var (
+	_ Converter = (*HTMLToDocxConverter)(nil)
+	_ Converter = (*HTMLToPDFConverter)(nil)
+	_ Converter = (*HTMLToMarkdownConverter)(nil)
+)
+
+// Format describes an output format in the registry.
+type Format struct {
+	Name       string                          // e.g. "docx"
+	Extensions []string                        // file extensions without the dot, e.g. "md", "markdown"
+	New        func(opts ...Options) Converter // creates a converter for the format
+}
+
+var (
+	formatsMu sync.RWMutex
+	formats   = map[string]Format{}
+)
+
+func init() {
+	RegisterFormat(Format{
+		Name:       "docx",
+		Extensions: []string{"docx"},
+		New:        func(opts ...Options) Converter { return NewHTMLToDocxConverter(opts...) },
+	})
+	RegisterFormat(Format{
+		Name:       "pdf",
+		Extensions: []string{"pdf"},
+		New:        func(opts ...Options) Converter { return NewHTMLToPDFConverter(opts...) },
+	})
+	RegisterFormat(Format{
+		Name:       "markdown",
+		Extensions: []string{"md", "markdown"},
+		New:        func(opts ...Options) Converter { return NewHTMLToMarkdownConverter(opts...) },
+	})
+}
+
+// RegisterFormat adds an output format to the registry, replacing any format
+// registered under the same name.
+func RegisterFormat(f Format) {
+	formatsMu.Lock()
+	defer formatsMu.Unlock()
+	formats[strings.ToLower(f.Name)] = f
+}
+
+// LookupFormat finds a registered format by name or file extension, with or
+// without the leading dot. Matching is case-insensitive.
+func LookupFormat(name string) (Format, bool) {
+	name = strings.TrimPrefix(strings.ToLower(strings.TrimSpace(name)), ".")
+	formatsMu.RLock()
+	defer formatsMu.RUnlock()
+	if f, ok := formats[name]; ok {
+		return f, true
+	}
+	for _, f := range formats {
+		for _, ext := range f.Extensions {
+			if strings.EqualFold(strings.TrimPrefix(ext, "."), name) {
+				return f, true
+			}
+		}
+	}
+	return Format{}, false
+}
+
+// FormatForFile finds the registered format for a file name by its extension.
+func FormatForFile(filename string) (Format, bool) {
+	ext := filepath.Ext(filename)
+	if ext == "" {
+		return Format{}, false
+	}
+	return LookupFormat(ext)
+}
+
+// Formats returns the registered formats sorted by name.
+func Formats() []Format {
+	formatsMu.RLock()
+	defer formatsMu.RUnlock()
+	list := make([]Format, 0, len(formats))
+	for _, f := range formats {
+		list = append(list, f)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+	return list
+}
+
+// NewConverter creates a converter for a format given by name or file
+// extension, see LookupFormat.
+func NewConverter(format string, opts ...Options) (Converter, error) {
+	f, ok := LookupFormat(format)
+	if !ok {
+		return nil, fmt.Errorf("unknown output format %q", format)
+	}
+	return f.New(opts...), nil
+}
+
+// countingWriter counts the bytes written through it, for WriteTo methods
+// backed by libraries that only report errors.
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (cw *countingWriter) Write(p []byte) (int, error) {
+	n, err := cw.w.Write(p)
+	cw.n += int64(n)
+	return n, err
+}
@@ -0,0 +1,61 @@
+package converter
+
+import (
+	"bytes"
+	"path/filepath"
+	"testing"
+)
+
+func TestLookupFormat(t *testing.T) {
+	tests := []struct {
+		name     string
+		expected string
+	}{
+		{"docx", "docx"},
+		{"PDF", "pdf"},
+		{".md", "markdown"},
+		{"markdown", "markdown"},
+	}
+
+	for _, tc := range tests {
+		f, ok := LookupFormat(tc.name)
+		if !ok || f.Name != tc.expected {
+			t.Errorf("LookupFormat(%q) = %q, %v, want %q", tc.name, f.Name, ok, tc.expected)
+		}
+	}
+	if _, ok := LookupFormat("odt"); ok {
+		t.Error("expected odt not to be registered")
+	}
+	if f, ok := FormatForFile("out/report.DOCX"); !ok || f.Name != "docx" {
+		t.Errorf("FormatForFile(report.DOCX) = %q, %v", f.Name, ok)
+	}
+}
+
+func TestNewConverter(t *testing.T) {
+	sections := HTMLSections([]string{`<html><body><h1>Title</h1><p>Paragraph.</p></body></html>`})
+
+	for _, f := range Formats() {
+		conv, err := NewConverter(f.Name)
+		if err != nil {
+			t.Fatalf("NewConverter(%q) failed: %v", f.Name, err)
+		}
+		if err := conv.ConvertSections(sections); err != nil {
+			t.Fatalf("%s: ConvertSections failed: %v", f.Name, err)
+		}
+		var buf bytes.Buffer
+		n, err := conv.WriteTo(&buf)
+		if err != nil {
+			t.Fatalf("%s: WriteTo failed: %v", f.Name, err)
+		}
+		if n != int64(buf.Len()) {
+			t.Errorf("%s: WriteTo returned %d, wrote %d bytes", f.Name, n, buf.Len())
+		}
+		if err := conv.SaveToFile(filepath.Join(t.TempDir(), "out."+f.Extensions[0])); err != nil {
+			t.Fatalf("%s: SaveToFile failed: %v", f.Name, err)
+		}
+	}
+
+	if _, err := NewConverter("odt"); err == nil {
+		t.Error("expected an error for an unknown format")
+	}
+}
@@ -0,0 +1,57 @@
+package converter
+
+import (
+	"context"
+	"fmt"
+)
+
+// Engine converts HTML with a fixed configuration. The converters hold the
+// document they build and are meant for one conversion each; an Engine
+// creates a fresh converter for every conversion instead, so one configured
+// Engine can be shared, for example by the handlers of a web server.
+//
+// An Engine is safe for concurrent use as long as its constructor is.
+type Engine struct {
+	newConverter func() (Converter, error)
+}
+
+// NewEngine returns an engine that creates its converters with newConverter,
+// which configures each converter the same way: options, fonts, handlers.
+//
+//	engine := converter.NewEngine(func() (converter.Converter, error) {
+//		c := converter.NewHTMLToPDFConverter(opts)
+//		return c, c.RegisterFont("DejaVu Sans", files)
+//	})
+func NewEngine(newConverter func() (Converter, error)) *Engine {
+	return &Engine{newConverter: newConverter}
+}
+
+// NewFormatEngine returns an engine for a registered format, given by name
+// or file extension, with the given options.
+func NewFormatEngine(format string, opts ...Options) (*Engine, error) {
+	f, ok := LookupFormat(format)
+	if !ok {
+		return nil, fmt.Errorf("unknown output format %q", format)
+	}
+	return NewEngine(func() (Converter, error) { return f.New(opts...), nil }), nil
+}
+
+// Convert converts HTML strings with a new converter and returns it, holding
+// the converted document and its warnings. Each string starts a new
+// section, see HTMLSections.
+func (e *Engine) Convert(ctx context.Context, htmlContents []string) (Converter, error) {
+	return e.ConvertSections(ctx, HTMLSections(htmlContents))
+}
+
+// ConvertSections converts sections with a new converter and returns it, like
+// Convert.
+func (e *Engine) ConvertSections(ctx context.Context, sections []Section) (Converter, error) {
+	conv, err := e.newConverter()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create converter: %w", err)
+	}
+	if err := conv.ConvertSectionsContext(ctx, sections); err != nil {
+		return nil, err
+	}
+	return conv, nil
+}
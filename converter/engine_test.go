@@ -0,0 +1,74 @@
+package converter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+
+	"golang.org/x/net/html"
+)
+
+func TestEngineConcurrent(t *testing.T) {
+	engine, err := NewFormatEngine("md")
+	if err != nil {
+		t.Fatalf("NewFormatEngine failed: %v", err)
+	}
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 8)
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			conv, err := engine.Convert(context.Background(), []string{fmt.Sprintf("<h1>Doc %d</h1>", i)})
+			if err != nil {
+				errs <- err
+				return
+			}
+			out, err := conv.Bytes()
+			if err != nil {
+				errs <- err
+				return
+			}
+			if want := fmt.Sprintf("# Doc %d", i); string(out) != want {
+				errs <- fmt.Errorf("expected %q, got %q", want, out)
+			}
+		}(i)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Error(err)
+	}
+}
+
+func TestEngineConfigure(t *testing.T) {
+	engine := NewEngine(func() (Converter, error) {
+		c := NewHTMLToDocxConverter()
+		c.Handle("signature", func(n *html.Node, ctx *DocxContext) error {
+			ctx.AddText("Signed")
+			return nil
+		})
+		return c, nil
+	})
+	for i := 0; i < 2; i++ {
+		conv, err := engine.Convert(context.Background(), []string{`<p>Body</p><signature></signature>`})
+		if err != nil {
+			t.Fatalf("Convert failed: %v", err)
+		}
+		if _, err := conv.Bytes(); err != nil {
+			t.Fatalf("Bytes failed: %v", err)
+		}
+	}
+
+	failing := NewEngine(func() (Converter, error) { return nil, errors.New("no fonts") })
+	if _, err := failing.Convert(context.Background(), []string{"<p>x</p>"}); err == nil || !strings.Contains(err.Error(), "no fonts") {
+		t.Errorf("expected the constructor error, got %v", err)
+	}
+	if _, err := NewFormatEngine("odt"); err == nil {
+		t.Error("expected an unknown format to fail")
+	}
+}
@@ -1,28 +1,113 @@
 package converter
 
 import (
+	"bytes"
+	"context"
+	"encoding/xml"
 	"fmt"
+	"io"
+	"strconv"
 	"strings"
 
+	"baliance.com/gooxml"
 	"baliance.com/gooxml/color"
 	"baliance.com/gooxml/document"
 	"baliance.com/gooxml/measurement"
 	"baliance.com/gooxml/schema/soo/ofc/sharedTypes"
+	"baliance.com/gooxml/schema/soo/pkg/metadata/docPropsCore"
 	"baliance.com/gooxml/schema/soo/wml"
-	"golang.org/x/net/html"
 )
 
 // HTMLToDocxConverter converts HTML content to DOCX format.
+// It builds a single document, which each conversion adds to, and is not
+// safe for concurrent use; an Engine creates a fresh converter per conversion.
 type HTMLToDocxConverter struct {
-	doc *document.Document
+	doc     *document.Document
+	page    PageSetup                  // page setup of the current section
+	start   int                        // Options.Page.PageNumberStart, until the first section
+	headers map[string]document.Header // keyed by PageVariant
+	footers map[string]document.Footer // keyed by PageVariant
+	dir     string                     // "rtl" or "ltr" from a dir attribute, "" to detect from the text
+	lang    string                     // language of the current element when it differs from the document's
+	docLang string                     // document default language from <html lang>
+	meta    Metadata                   // document properties, from Options and the HTML
+	style   Style                      // inline style of the current element
+	guard   guard                      // cancellation and resource limits
+	classes map[string]Style           // formatting by class name, see Options.ClassStyles
+	written bool                       // the body section holds the last section converted so far
+
+	handlers map[string]DocxHandler // custom element handlers, see Handle
+	bypass   *Element               // element a handler delegated to the built-in handling
 }
 
-// NewHTMLToDocxConverter creates a new DOCX converter with default page settings.
-func NewHTMLToDocxConverter() *HTMLToDocxConverter {
-	doc := document.New()
-	section := doc.BodySection()
-	section.SetPageMargins(measurement.Inch, measurement.Inch, measurement.Inch, measurement.Inch, 0, 0, 0)
-	return &HTMLToDocxConverter{doc: doc}
+// docxDefaultPage is the page setup used when none is given: Letter with
+// one-inch margins, and headers and footers half an inch from the page edge.
+var docxDefaultPage = PageSetup{
+	Size:           PageLetter,
+	Orientation:    Portrait,
+	Margins:        &Margins{Top: 25.4, Right: 25.4, Bottom: 25.4, Left: 25.4},
+	HeaderDistance: 12.7,
+	FooterDistance: 12.7,
+}
+
+// pageVariants lists header/footer variants in the order they are written.
+var pageVariants = []string{"default", "first", "even"}
+
+// NewHTMLToDocxConverter creates a new DOCX converter. Without options it
+// uses Letter pages with one-inch margins and Word's default font.
+func NewHTMLToDocxConverter(opts ...Options) *HTMLToDocxConverter {
+	o := firstOptions(opts)
+	c := &HTMLToDocxConverter{
+		doc:     document.New(),
+		page:    o.Page.Merge(docxDefaultPage),
+		start:   o.Page.PageNumberStart,
+		meta:    o.Metadata,
+		guard:   guard{limits: o.Limits, strict: o.Strict},
+		classes: o.ClassStyles,
+		headers: map[string]document.Header{},
+		footers: map[string]document.Footer{},
+	}
+	c.setDefaultFont(o.FontFamily, o.FontSize)
+	return c
+}
+
+// defaultRunProperties returns the document's default run properties,
+// creating them if the template has none.
+func (c *HTMLToDocxConverter) defaultRunProperties() *wml.CT_RPr {
+	styles := c.doc.Styles.X()
+	if styles.DocDefaults == nil {
+		styles.DocDefaults = wml.NewCT_DocDefaults()
+	}
+	if styles.DocDefaults.RPrDefault == nil {
+		styles.DocDefaults.RPrDefault = wml.NewCT_RPrDefault()
+	}
+	if styles.DocDefaults.RPrDefault.RPr == nil {
+		styles.DocDefaults.RPrDefault.RPr = wml.NewCT_RPr()
+	}
+	return styles.DocDefaults.RPrDefault.RPr
+}
+
+// setDefaultFont sets the document's default run font and size in points.
+// Empty or zero values keep the defaults of the underlying template.
+func (c *HTMLToDocxConverter) setDefaultFont(family string, size float64) {
+	if family == "" && size <= 0 {
+		return
+	}
+	rpr := c.defaultRunProperties()
+	if family != "" {
+		rpr.RFonts = wml.NewCT_Fonts()
+		rpr.RFonts.AsciiAttr = &family
+		rpr.RFonts.HAnsiAttr = &family
+		rpr.RFonts.EastAsiaAttr = &family
+		rpr.RFonts.CsAttr = &family
+	}
+	if size > 0 {
+		halfPoints := uint64(size*2 + 0.5)
+		rpr.Sz = wml.NewCT_HpsMeasure()
+		rpr.Sz.ValAttr.ST_UnsignedDecimalNumber = &halfPoints
+		rpr.SzCs = wml.NewCT_HpsMeasure()
+		rpr.SzCs.ValAttr.ST_UnsignedDecimalNumber = &halfPoints
+	}
 }
 
 // parseHexColor safely converts a hex color string.
@@ -36,245 +121,454 @@ func parseHexColor(hex string) color.Color {
 
 func uint64Ptr(u uint64) *uint64 { return &u }
 
-// Convert parses and converts multiple HTML strings to DOCX content.
+// Convert parses and converts multiple HTML strings to DOCX content. Each
+// string starts a new section on a new page, see ConvertSections.
 func (c *HTMLToDocxConverter) Convert(htmlContents []string) error {
-	for i, content := range htmlContents {
-		content = UnescapeUnicodeHTML(content)
-		root, err := html.Parse(strings.NewReader(content))
-		if err != nil {
-			return fmt.Errorf("failed to parse index %d: %w", i, err)
+	return c.ConvertSections(HTMLSections(htmlContents))
+}
+
+// ConvertContext is like Convert but stops with an error once ctx is done.
+func (c *HTMLToDocxConverter) ConvertContext(ctx context.Context, htmlContents []string) error {
+	return c.ConvertSectionsContext(ctx, HTMLSections(htmlContents))
+}
+
+// ConvertReader reads one HTML document from r, decoding it to UTF-8 as
+// described for ReadHTML, and converts it like Convert.
+func (c *HTMLToDocxConverter) ConvertReader(r io.Reader, contentType string) error {
+	content, err := ReadHTML(r, contentType)
+	if err != nil {
+		return err
+	}
+	return c.Convert([]string{content})
+}
+
+// ConvertSections converts each HTML input into its own DOCX section with its
+// own page setup, headers and footers. A section without headers or footers
+// keeps those of the previous one.
+func (c *HTMLToDocxConverter) ConvertSections(sections []Section) error {
+	return c.ConvertSectionsContext(context.Background(), sections)
+}
+
+// ConvertSectionsContext is like ConvertSections but stops with an error once
+// ctx is done or a limit set in Options.Limits is exceeded.
+func (c *HTMLToDocxConverter) ConvertSectionsContext(ctx context.Context, sections []Section) error {
+	docs, err := parseSections(ctx, sections, c.guard.limits, c.classes)
+	if err != nil {
+		return err
+	}
+	return c.convertDocuments(ctx, docs)
+}
+
+// convertDocuments renders documents already built from HTML, each as its own
+// section.
+func (c *HTMLToDocxConverter) convertDocuments(ctx context.Context, docs []*Document) error {
+	c.guard.start(ctx)
+	if c.written && len(docs) > 0 {
+		c.closeBodySection()
+	}
+	for i, doc := range docs {
+		c.page = doc.Page.Merge(c.page)
+		if c.page.PageNumberStart == 0 {
+			c.page.PageNumberStart = c.start
+		}
+		c.start = 0
+		c.meta = c.meta.Merge(doc.Metadata)
+		if c.docLang == "" && doc.Lang != "" {
+			// The first document's language becomes the default for all text.
+			c.docLang = doc.Lang
+			c.defaultRunProperties().Lang = docxLanguage(doc.Lang)
+		}
+		for _, variant := range pageVariants {
+			for _, region := range doc.Headers[variant] {
+				c.walk(region, nil, c.header(variant), wml.ST_JcLeft)
+			}
+			for _, region := range doc.Footers[variant] {
+				c.walk(region, nil, c.footer(variant), wml.ST_JcLeft)
+			}
 		}
-		c.walk(root, nil, nil, wml.ST_JcLeft)
-		if i < len(htmlContents)-1 {
-			c.addPageBreak()
+		c.walk(doc.Body, nil, nil, wml.ST_JcLeft)
+		if c.guard.err != nil {
+			return c.guard.err
+		}
+		if i < len(docs)-1 {
+			c.finishSection(c.addSectionBreak())
+		} else {
+			c.finishSection(c.doc.BodySection())
+			c.written = true
 		}
 	}
+	c.applyMetadata()
 	return nil
 }
 
-func (c *HTMLToDocxConverter) walk(n *html.Node, para *document.Paragraph, container interface{}, align wml.ST_Jc) {
-	if n.Type == html.TextNode {
-		text := strings.TrimSpace(n.Data)
-		if text != "" {
+// applyMetadata records the document metadata in the core and app properties.
+func (c *HTMLToDocxConverter) applyMetadata() {
+	core := c.doc.CoreProperties
+	if c.meta.Title != "" {
+		core.SetTitle(c.meta.Title)
+	}
+	if c.meta.Author != "" {
+		core.SetAuthor(c.meta.Author)
+	}
+	if c.meta.Description != "" {
+		core.SetDescription(c.meta.Description)
+	}
+	if c.meta.Subject != "" {
+		core.X().Subject = &gooxml.XSDAny{
+			XMLName: xml.Name{Space: "http://purl.org/dc/elements/1.1/", Local: "subject"},
+			Data:    []byte(c.meta.Subject),
+		}
+	}
+	if len(c.meta.Keywords) > 0 {
+		core.X().Keywords = docPropsCore.NewCT_Keywords()
+		core.X().Keywords.Value = []*docPropsCore.CT_Keyword{{Content: strings.Join(c.meta.Keywords, ", ")}}
+	}
+	if c.meta.Creator != "" {
+		c.doc.AppProperties.SetApplication(c.meta.Creator)
+	}
+}
+
+// walk renders an element of the document model. Inline content goes into
+// para, or into a new paragraph of container when para is nil.
+func (c *HTMLToDocxConverter) walk(e *Element, para *document.Paragraph, container interface{}, align wml.ST_Jc) {
+	if !c.guard.enter() {
+		return
+	}
+	if e.Kind == KindText {
+		if strings.TrimSpace(e.Text) != "" {
 			if para == nil {
 				p := c.createParagraph(container)
-				p.Properties().SetAlignment(align)
+				c.setDirection(p, e.Text, align)
 				para = &p
 			}
-			para.AddRun().AddText(n.Data)
+			c.addText(para, e.Text)
 		}
 		return
 	}
 
-	if n.Type == html.ElementNode {
-		attrs := GetAttrMap(n.Attr)
-		currentAlign := align
-		if val, ok := attrs["align"]; ok {
-			switch strings.ToLower(val) {
-			case "center":
-				currentAlign = wml.ST_JcCenter
-			case "right":
-				currentAlign = wml.ST_JcRight
-			case "left":
-				currentAlign = wml.ST_JcLeft
-			}
+	align = docxAlign(e.Align, align)
+	if e.Dir != "" {
+		oldDir := c.dir
+		c.dir = e.Dir
+		defer func() { c.dir = oldDir }()
+	}
+	if e.Lang != "" {
+		oldLang := c.lang
+		c.lang = e.Lang
+		if strings.EqualFold(e.Lang, c.docLang) {
+			c.lang = ""
 		}
+		defer func() { c.lang = oldLang }()
+	}
 
-		nodeType := EffectiveNodeType(n)
-		switch nodeType {
-		case "header":
-			hdr := c.doc.AddHeader()
-			c.doc.BodySection().SetHeader(hdr, wml.ST_HdrFtrDefault)
-			c.processChildren(n, nil, hdr, currentAlign)
-			return
-		case "footer":
-			ftr := c.doc.AddFooter()
-			c.doc.BodySection().SetFooter(ftr, wml.ST_HdrFtrDefault)
-			c.processChildren(n, nil, ftr, currentAlign)
-			return
-		case "center":
-			c.processChildren(n, para, container, wml.ST_JcCenter)
-			return
-		case "p", "section", "article", "nav", "main", "body", "html":
-			p := c.createParagraph(container)
-			p.Properties().SetAlignment(currentAlign)
-			c.processChildren(n, &p, container, currentAlign)
-			return
-		case "div", "span":
-			c.processChildren(n, para, container, align)
-			return
-		case "h1", "h2", "h3", "h4", "h5", "h6":
-			p := c.createParagraph(container)
-			p.SetStyle("Heading" + nodeType[1:])
-			p.Properties().SetAlignment(currentAlign)
-
-			c.processChildren(n, &p, container, currentAlign)
-
-			size := 12.0
-			switch nodeType {
-			case "h1":
-				size = 24
-			case "h2":
-				size = 18
-			case "h3":
-				size = 14
-			case "h4":
-				size = 12
-			case "h5":
-				size = 10
-			case "h6":
-				size = 8
+	if h := c.handlers[e.Type]; h != nil && c.bypass != e {
+		ctx := &DocxContext{Document: c.doc, Paragraph: para, Container: container, Element: e, c: c, align: align}
+		if err := h(e.Source, ctx); err != nil {
+			c.guard.fail(handlerError(e.Type, err))
+		}
+		return
+	}
+	c.bypass = nil
+
+	oldStyle := c.style
+	c.style = c.style.merge(e.Style)
+	defer func() { c.style = oldStyle }()
+
+	switch e.Kind {
+	case KindRegion:
+		// Rendered on the pages, see Document.Headers.
+	case KindUnsupported:
+		c.guard.warn(e.Source, e.Reason)
+	case KindImage:
+		c.guard.warn(e.Source, "images are not supported in DOCX output")
+	case KindField:
+		c.addPageField(e.Field, para, container, align)
+	case KindBreak:
+		if para != nil {
+			para.AddRun().AddBreak()
+		}
+	case KindParagraph, KindPre:
+		p := c.createParagraph(container)
+		align = c.setDirection(p, e.TextContent(), align)
+		c.processChildren(e, &p, container, align)
+	case KindHeading:
+		p := c.createParagraph(container)
+		p.SetStyle("Heading" + strconv.Itoa(e.Level))
+		align = c.setDirection(p, e.TextContent(), align)
+		c.style = c.style.merge(Style{Bold: true, FontSize: docxHeadingSizes[e.Level]})
+		c.processChildren(e, &p, container, align)
+	case KindRule:
+		p := c.createParagraph(container)
+		c.applyHRStyle(&p, e.RuleSize, e.Style.Color)
+	case KindTable:
+		c.processTable(e, container, align)
+		if cell, ok := container.(document.Cell); ok {
+			// A cell must end with a paragraph, which also keeps any text
+			// after the table below it.
+			p := cell.AddParagraph()
+			if para != nil {
+				*para = p
 			}
+		}
+	case KindList:
+		c.processList(e, container, align)
+	default:
+		c.processChildren(e, para, container, align)
+	}
+}
 
-			for _, r := range p.Runs() {
-				r.Properties().SetBold(true)
-				r.Properties().SetSize(measurement.Distance(size))
+// docxAlign maps an element's align attribute to a paragraph alignment,
+// returning def when it has none.
+func docxAlign(align string, def wml.ST_Jc) wml.ST_Jc {
+	switch align {
+	case "center":
+		return wml.ST_JcCenter
+	case "right":
+		return wml.ST_JcRight
+	case "left":
+		return wml.ST_JcLeft
+	}
+	return def
+}
+
+// docxHeadingSizes are the font sizes of headings by level, in points.
+var docxHeadingSizes = [7]float64{1: 24, 2: 18, 3: 14, 4: 12, 5: 10, 6: 8}
+
+// setDirection aligns a paragraph and returns the alignment its content
+// inherits. Paragraphs reading right to left, by their dir attribute or else
+// by their text, are marked bidi and right aligned unless aligned otherwise.
+func (c *HTMLToDocxConverter) setDirection(p document.Paragraph, text string, align wml.ST_Jc) wml.ST_Jc {
+	if c.dir == "rtl" || (c.dir == "" && IsRTLText(text)) {
+		p.Properties().X().Bidi = wml.NewCT_OnOff()
+		if align == wml.ST_JcLeft {
+			align = wml.ST_JcRight
+		}
+	}
+	p.Properties().SetAlignment(align)
+	return align
+}
+
+// addText adds a run of text in the current style to a paragraph, marking it
+// right to left when it holds right-to-left characters so Word orders it
+// correctly. Line breaks in monospaced text are kept.
+func (c *HTMLToDocxConverter) addText(para *document.Paragraph, text string) document.Run {
+	r := para.AddRun()
+	if c.style.Code {
+		for i, line := range strings.Split(text, "\n") {
+			if i > 0 {
+				r.AddBreak()
 			}
-			return
-		case "hr":
-			p := c.createParagraph(container)
-			c.applyHRStyle(&p, attrs)
-			return
-		case "table":
-			c.processTable(n, currentAlign)
-			return
-		case "ul", "ol":
-			c.processList(n, nodeType == "ol", container, currentAlign)
-			return
-		case "b", "strong":
-			c.formatted(n, para, "bold", container, currentAlign)
-			return
-		case "i", "em":
-			c.formatted(n, para, "italic", container, currentAlign)
-			return
-		case "u":
-			c.formatted(n, para, "underline", container, currentAlign)
-			return
-		case "font":
-			c.processFont(n, para, container, currentAlign)
-			return
+			r.AddText(line)
+		}
+	} else {
+		r.AddText(text)
+	}
+	if HasRTL(text) {
+		r.Properties().X().Rtl = wml.NewCT_OnOff()
+	}
+	if c.lang != "" {
+		r.Properties().X().Lang = docxLanguage(c.lang)
+	}
+
+	props := r.Properties()
+	if c.style.Bold {
+		props.SetBold(true)
+	}
+	if c.style.Italic {
+		props.SetItalic(true)
+	}
+	if c.style.Underline {
+		props.SetUnderline(wml.ST_UnderlineSingle, color.Auto)
+	}
+	if c.style.Color != "" {
+		props.SetColor(parseHexColor(c.style.Color))
+	}
+	if c.style.FontSize > 0 {
+		props.SetSize(measurement.Distance(c.style.FontSize))
+	}
+	if family := docxFontFamily(c.style.FontFamily); c.style.Code {
+		props.SetFontFamily("Courier New")
+	} else if family != "" {
+		props.SetFontFamily(family)
+	}
+	return r
+}
+
+// docxFontFamily returns the first family of a <font face> or CSS font-family
+// list, with the generic CSS families mapped to fonts Word ships with.
+func docxFontFamily(names string) string {
+	name, _, _ := strings.Cut(names, ",")
+	name = strings.Trim(strings.TrimSpace(name), `"'`)
+	switch strings.ToLower(name) {
+	case "sans-serif":
+		return "Arial"
+	case "serif":
+		return "Times New Roman"
+	case "monospace":
+		return "Courier New"
+	}
+	return name
+}
+
+// docxLanguage returns a w:lang element for a BCP 47 language tag, setting it
+// for the script class Word checks the language of: right-to-left, East
+// Asian or other text.
+func docxLanguage(tag string) *wml.CT_Language {
+	lang := wml.NewCT_Language()
+	primary, _, _ := strings.Cut(strings.ToLower(tag), "-")
+	switch primary {
+	case "ar", "he", "fa", "ur", "yi", "ps", "syr", "dv":
+		lang.BidiAttr = &tag
+	case "zh", "ja", "ko":
+		lang.EastAsiaAttr = &tag
+	default:
+		lang.ValAttr = &tag
+	}
+	return lang
+}
+
+// header returns the current section's header for a page variant, creating
+// it the first time it is requested. Later <header> elements for the same
+// variant add to its content.
+func (c *HTMLToDocxConverter) header(variant string) document.Header {
+	if hdr, ok := c.headers[variant]; ok {
+		return hdr
+	}
+	hdr := c.doc.AddHeader()
+	c.headers[variant] = hdr
+	return hdr
+}
+
+// footer returns the current section's footer for a page variant, like header.
+func (c *HTMLToDocxConverter) footer(variant string) document.Footer {
+	if ftr, ok := c.footers[variant]; ok {
+		return ftr
+	}
+	ftr := c.doc.AddFooter()
+	c.footers[variant] = ftr
+	return ftr
+}
+
+// addSectionBreak ends the current section with a next-page section break
+// and returns it so its properties can be filled in.
+func (c *HTMLToDocxConverter) addSectionBreak() document.Section {
+	p := c.doc.AddParagraph()
+	return p.Properties().AddSection(wml.ST_SectionMarkNextPage)
+}
+
+// closeBodySection ends the section an earlier conversion wrote to the body
+// with a section break, so the next conversion starts a section of its own.
+func (c *HTMLToDocxConverter) closeBodySection() {
+	body := c.doc.X().Body
+	p := c.doc.AddParagraph()
+	p.Properties().X().SectPr = body.SectPr
+	body.SectPr = wml.NewCT_SectPr()
+}
+
+// finishSection writes the current page setup, headers and footers to sect
+// and starts collecting headers and footers for the next section.
+func (c *HTMLToDocxConverter) finishSection(sect document.Section) {
+	width, height := c.page.Size.Width, c.page.Size.Height
+	orient := wml.ST_PageOrientationPortrait
+	if c.page.Orientation == Landscape {
+		width, height = height, width
+		orient = wml.ST_PageOrientationLandscape
+	}
+	sect.X().PgSz = wml.NewCT_PageSz()
+	sect.X().PgSz.WAttr = twips(width)
+	sect.X().PgSz.HAttr = twips(height)
+	sect.X().PgSz.OrientAttr = orient
+
+	m := c.page.Margins
+	sect.SetPageMargins(mm(m.Top), mm(m.Right), mm(m.Bottom), mm(m.Left), mm(c.page.HeaderDistance), mm(c.page.FooterDistance), 0)
+
+	if c.page.PageNumberStart > 0 {
+		start := int64(c.page.PageNumberStart)
+		sect.X().PgNumType = wml.NewCT_PageNumber()
+		sect.X().PgNumType.StartAttr = &start
+	}
+
+	for _, variant := range pageVariants {
+		if hdr, ok := c.headers[variant]; ok {
+			sect.SetHeader(hdr, c.hdrFtrType(sect, variant))
 		}
+		if ftr, ok := c.footers[variant]; ok {
+			sect.SetFooter(ftr, c.hdrFtrType(sect, variant))
+		}
+	}
+	c.headers = map[string]document.Header{}
+	c.footers = map[string]document.Footer{}
+}
+
+// mm converts millimeters to a gooxml distance.
+func mm(v float64) measurement.Distance {
+	return measurement.Distance(v) * measurement.Millimeter
+}
+
+// twips converts millimeters to a twips measure as used by page sizes.
+func twips(v float64) *sharedTypes.ST_TwipsMeasure {
+	t := uint64(v/25.4*1440 + 0.5)
+	return &sharedTypes.ST_TwipsMeasure{ST_UnsignedDecimalNumber: &t}
+}
+
+// hdrFtrType maps a page variant to its header/footer type, enabling the
+// section or document setting Word needs to use that variant.
+func (c *HTMLToDocxConverter) hdrFtrType(sect document.Section, variant string) wml.ST_HdrFtr {
+	switch variant {
+	case "first":
+		sect.X().TitlePg = wml.NewCT_OnOff()
+		return wml.ST_HdrFtrFirst
+	case "even":
+		c.doc.Settings.X().EvenAndOddHeaders = wml.NewCT_OnOff()
+		return wml.ST_HdrFtrEven
 	}
-	c.processChildren(n, para, container, align)
+	return wml.ST_HdrFtrDefault
 }
 
-func (c *HTMLToDocxConverter) processFont(n *html.Node, para *document.Paragraph, container interface{}, align wml.ST_Jc) {
+// addPageField emits a PAGE or NUMPAGES field in place of a placeholder element.
+func (c *HTMLToDocxConverter) addPageField(field string, para *document.Paragraph, container interface{}, align wml.ST_Jc) {
 	if para == nil {
 		p := c.createParagraph(container)
 		p.Properties().SetAlignment(align)
 		para = &p
 	}
-	attrs := GetAttrMap(n.Attr)
-
-	for ch := n.FirstChild; ch != nil; ch = ch.NextSibling {
-		if ch.Type == html.TextNode {
-			text := strings.TrimSpace(ch.Data)
-			if text != "" {
-				r := para.AddRun()
-				r.AddText(ch.Data)
-
-				if val, ok := attrs["size"]; ok {
-					sz := 12.0
-					switch val {
-					case "1":
-						sz = 8
-					case "2":
-						sz = 10
-					case "3":
-						sz = 12
-					case "4":
-						sz = 14
-					case "5":
-						sz = 18
-					case "6":
-						sz = 24
-					case "7":
-						sz = 36
-					}
-					r.Properties().SetSize(measurement.Distance(sz))
-				}
-				if val, ok := attrs["color"]; ok {
-					r.Properties().SetColor(parseHexColor(val))
-				}
-				if val, ok := attrs["face"]; ok {
-					r.Properties().SetFontFamily(val)
-				}
-			}
-		} else {
-			c.walk(ch, para, container, align)
-		}
+	code := document.FieldCurrentPage
+	if field == "pages" {
+		code = document.FieldNumberOfPages
 	}
+	para.AddRun().AddField(code)
 }
 
-func (c *HTMLToDocxConverter) processTable(n *html.Node, align wml.ST_Jc) {
-	table := c.doc.AddTable()
+func (c *HTMLToDocxConverter) processTable(e *Element, container interface{}, align wml.ST_Jc) {
+	table := c.createTable(container)
 	table.Properties().SetWidthPercent(100)
-	attrs := GetAttrMap(n.Attr)
 
-	if b, ok := attrs["border"]; ok && b != "0" {
+	if e.Border {
 		table.Properties().Borders().SetAll(wml.ST_BorderSingle, color.Auto, 1*measurement.Point)
 	}
 
-	if align == wml.ST_JcCenter || attrs["align"] == "center" {
+	if align == wml.ST_JcCenter {
 		table.Properties().SetAlignment(wml.ST_JcTableCenter)
 	}
 
-	var walkTable func(*html.Node)
-	walkTable = func(curr *html.Node) {
-		for ch := curr.FirstChild; ch != nil; ch = ch.NextSibling {
-			if ch.Type == html.ElementNode {
-				chType := EffectiveNodeType(ch)
-				if chType == "tr" {
-					row := table.AddRow()
-					trAttrs := GetAttrMap(ch.Attr)
-
-					var collectCells func(*html.Node)
-					collectCells = func(cellContainer *html.Node) {
-						for cellNode := cellContainer.FirstChild; cellNode != nil; cellNode = cellNode.NextSibling {
-							if cellNode.Type == html.ElementNode {
-								cellType := EffectiveNodeType(cellNode)
-								if cellType == "td" || cellType == "th" {
-									cell := row.AddCell()
-									tdAttrs := GetAttrMap(cellNode.Attr)
-
-									bg := ""
-									if val, ok := tdAttrs["bgcolor"]; ok {
-										bg = val
-									} else if val, ok := trAttrs["bgcolor"]; ok {
-										bg = val
-									}
-									if bg != "" {
-										cell.Properties().SetShading(wml.ST_ShdSolid, parseHexColor(bg), color.Auto)
-									}
-
-									p := cell.AddParagraph()
-									cellAlign := wml.ST_JcLeft
-									if tdAttrs["align"] == "center" {
-										cellAlign = wml.ST_JcCenter
-									}
-									p.Properties().SetAlignment(cellAlign)
-
-									if cellType == "th" {
-										p.AddRun().Properties().SetBold(true)
-									}
-									c.processChildren(cellNode, &p, nil, cellAlign)
-								} else if cellType == "div" || cellType == "span" {
-									collectCells(cellNode)
-								}
-							}
-						}
-					}
-					collectCells(ch)
-				} else if ch.FirstChild != nil {
-					walkTable(ch)
-				}
+	for _, tr := range e.Children {
+		row := table.AddRow()
+		for _, td := range tr.Children {
+			cell := row.AddCell()
+			if td.Background != "" {
+				cell.Properties().SetShading(wml.ST_ShdSolid, parseHexColor(td.Background), color.Auto)
 			}
+			p := cell.AddParagraph()
+			cellAlign := docxAlign(td.Align, wml.ST_JcLeft)
+			p.Properties().SetAlignment(cellAlign)
+			c.walk(td, &p, cell, cellAlign)
 		}
 	}
-	walkTable(n)
 }
 
-func (c *HTMLToDocxConverter) applyHRStyle(p *document.Paragraph, attrs map[string]string) {
+// applyHRStyle draws a horizontal rule as the bottom border of an empty
+// paragraph, size points thick in the given color, or gray if color is empty.
+func (c *HTMLToDocxConverter) applyHRStyle(p *document.Paragraph, size int, hexColor string) {
 	if p.X().PPr == nil {
 		p.X().PPr = wml.NewCT_PPr()
 	}
@@ -291,20 +585,14 @@ func (c *HTMLToDocxConverter) applyHRStyle(p *document.Paragraph, attrs map[stri
 	p.X().PPr.PBdr.Bottom.ValAttr = wml.ST_BorderSingle
 
 	thickness := uint64(4)
-	if val, ok := attrs["size"]; ok {
-		var t uint64
-		if _, err := fmt.Sscanf(val, "%d", &t); err == nil && t > 0 {
-			thickness = t * 2
-		}
+	if size > 0 {
+		thickness = uint64(size) * 2
 	}
 	p.X().PPr.PBdr.Bottom.SzAttr = uint64Ptr(thickness)
 
 	colorStr := "808080"
-	if val, ok := attrs["color"]; ok {
-		clean := strings.TrimPrefix(val, "#")
-		if len(clean) == 6 {
-			colorStr = clean
-		}
+	if clean := strings.TrimPrefix(hexColor, "#"); len(clean) == 6 {
+		colorStr = clean
 	}
 	p.X().PPr.PBdr.Bottom.ColorAttr = &wml.ST_HexColor{ST_HexColorRGB: &colorStr}
 
@@ -316,52 +604,47 @@ func (c *HTMLToDocxConverter) applyHRStyle(p *document.Paragraph, attrs map[stri
 	p.X().PPr.Spacing.AfterAttr = &sharedTypes.ST_TwipsMeasure{ST_UnsignedDecimalNumber: &zero}
 }
 
-func (c *HTMLToDocxConverter) processChildren(n *html.Node, para *document.Paragraph, container interface{}, align wml.ST_Jc) {
-	for ch := n.FirstChild; ch != nil; ch = ch.NextSibling {
+func (c *HTMLToDocxConverter) processChildren(e *Element, para *document.Paragraph, container interface{}, align wml.ST_Jc) {
+	for _, ch := range e.Children {
 		c.walk(ch, para, container, align)
 	}
 }
 
-func (c *HTMLToDocxConverter) formatted(n *html.Node, para *document.Paragraph, style string, container interface{}, align wml.ST_Jc) {
-	if para == nil {
+func (c *HTMLToDocxConverter) processList(e *Element, container interface{}, align wml.ST_Jc) {
+	for i, li := range e.Children {
 		p := c.createParagraph(container)
 		p.Properties().SetAlignment(align)
-		para = &p
-	}
-	for ch := n.FirstChild; ch != nil; ch = ch.NextSibling {
-		if ch.Type == html.TextNode {
-			r := para.AddRun()
-			r.AddText(ch.Data)
-			if style == "bold" {
-				r.Properties().SetBold(true)
-			}
-			if style == "italic" {
-				r.Properties().SetItalic(true)
-			}
-			if style == "underline" {
-				r.Properties().SetUnderline(wml.ST_UnderlineSingle, color.Auto)
-			}
-		} else {
-			c.walk(ch, para, container, align)
+		prefix := "• "
+		if e.Ordered {
+			prefix = fmt.Sprintf("%d. ", i+1)
 		}
+		p.AddRun().AddText(prefix)
+		c.walk(li, &p, container, align)
 	}
 }
 
-func (c *HTMLToDocxConverter) processList(n *html.Node, ordered bool, container interface{}, align wml.ST_Jc) {
-	index := 1
-	for li := n.FirstChild; li != nil; li = li.NextSibling {
-		if li.Type == html.ElementNode && EffectiveNodeType(li) == "li" {
-			p := c.createParagraph(container)
-			p.Properties().SetAlignment(align)
-			prefix := "• "
-			if ordered {
-				prefix = fmt.Sprintf("%d. ", index)
-				index++
-			}
-			p.AddRun().AddText(prefix)
-			c.processChildren(li, &p, container, align)
-		}
+// createTable adds a table to container. gooxml only adds tables to the
+// body, so a table for a header, footer or cell is moved there from the end
+// of the body.
+func (c *HTMLToDocxConverter) createTable(container interface{}) document.Table {
+	table := c.doc.AddTable()
+	detach := func() *wml.EG_ContentBlockContent {
+		body := c.doc.X().Body
+		last := body.EG_BlockLevelElts[len(body.EG_BlockLevelElts)-1]
+		body.EG_BlockLevelElts = body.EG_BlockLevelElts[:len(body.EG_BlockLevelElts)-1]
+		return last.EG_ContentBlockContent[0]
 	}
+	switch v := container.(type) {
+	case document.Header:
+		v.X().EG_ContentBlockContent = append(v.X().EG_ContentBlockContent, detach())
+	case document.Footer:
+		v.X().EG_ContentBlockContent = append(v.X().EG_ContentBlockContent, detach())
+	case document.Cell:
+		elts := wml.NewEG_BlockLevelElts()
+		elts.EG_ContentBlockContent = append(elts.EG_ContentBlockContent, detach())
+		v.X().EG_BlockLevelElts = append(v.X().EG_BlockLevelElts, elts)
+	}
+	return table
 }
 
 func (c *HTMLToDocxConverter) createParagraph(container interface{}) document.Paragraph {
@@ -371,14 +654,32 @@ func (c *HTMLToDocxConverter) createParagraph(container interface{}) document.Pa
 	if ftr, ok := container.(document.Footer); ok {
 		return ftr.AddParagraph()
 	}
+	if cell, ok := container.(document.Cell); ok {
+		return cell.AddParagraph()
+	}
 	return c.doc.AddParagraph()
 }
 
-func (c *HTMLToDocxConverter) addPageBreak() {
-	p := c.doc.AddParagraph()
-	run := p.AddRun()
-	run.X().EG_RunInnerContent = append(run.X().EG_RunInnerContent, &wml.EG_RunInnerContent{Br: wml.NewCT_Br()})
-	run.X().EG_RunInnerContent[len(run.X().EG_RunInnerContent)-1].Br.TypeAttr = wml.ST_BrTypePage
+// WriteTo writes the DOCX document to w.
+func (c *HTMLToDocxConverter) WriteTo(w io.Writer) (int64, error) {
+	cw := &countingWriter{w: w}
+	err := c.doc.Save(cw)
+	return cw.n, err
+}
+
+// Warnings returns the warnings about unsupported content collected by all
+// conversions so far.
+func (c *HTMLToDocxConverter) Warnings() []Warning {
+	return append([]Warning(nil), c.guard.warnings...)
+}
+
+// Bytes returns the DOCX document.
+func (c *HTMLToDocxConverter) Bytes() ([]byte, error) {
+	var buf bytes.Buffer
+	if err := c.doc.Save(&buf); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
 }
 
 // SaveToFile saves the DOCX document to a file.
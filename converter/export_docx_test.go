@@ -1,9 +1,15 @@
 package converter
 
 import (
+	"bytes"
 	"os"
 	"path/filepath"
+	"reflect"
+	"strings"
 	"testing"
+
+	"baliance.com/gooxml/document"
+	"baliance.com/gooxml/schema/soo/wml"
 )
 
 func TestDocxConverterBasic(t *testing.T) {
@@ -184,3 +190,391 @@ func TestDocxConverterHorizontalRule(t *testing.T) {
 		t.Fatalf("SaveToFile failed: %v", err)
 	}
 }
+
+func TestDocxConverterPageFields(t *testing.T) {
+	conv := NewHTMLToDocxConverter()
+	htmlContents := []string{`<html><body>
+		<header><p>Report</p></header>
+		<p>Body content</p>
+		<footer><p>Page <span data-field="page"></span> of <span data-field="pages"></span></p></footer>
+	</body></html>`}
+
+	if err := conv.Convert(htmlContents); err != nil {
+		t.Fatalf("Convert failed: %v", err)
+	}
+	footers := conv.doc.Footers()
+	if len(footers) != 1 {
+		t.Fatalf("expected one footer, got %d", len(footers))
+	}
+	var paras []*wml.CT_P
+	for _, c := range footers[0].X().EG_ContentBlockContent {
+		paras = append(paras, c.P...)
+	}
+	want := []string{document.FieldCurrentPage, document.FieldNumberOfPages}
+	if got := fieldCodes(paras); !reflect.DeepEqual(got, want) {
+		t.Errorf("expected fields %v in the footer, got %v", want, got)
+	}
+	var body []*wml.CT_P
+	for _, p := range conv.doc.Paragraphs() {
+		body = append(body, p.X())
+	}
+	if got := fieldCodes(body); len(got) != 0 {
+		t.Errorf("expected no fields in the body, got %v", got)
+	}
+
+	tmpFile := filepath.Join(t.TempDir(), "pagefields.docx")
+	if err := conv.SaveToFile(tmpFile); err != nil {
+		t.Fatalf("SaveToFile failed: %v", err)
+	}
+}
+
+// fieldCodes returns the codes of the fields in paragraphs, in order.
+func fieldCodes(paras []*wml.CT_P) []string {
+	var codes []string
+	for _, p := range paras {
+		for _, pc := range p.EG_PContent {
+			for _, rc := range pc.EG_ContentRunContent {
+				if rc.R == nil {
+					continue
+				}
+				for _, ic := range rc.R.EG_RunInnerContent {
+					if ic.InstrText != nil {
+						codes = append(codes, strings.TrimSpace(ic.InstrText.Content))
+					}
+				}
+			}
+		}
+	}
+	return codes
+}
+
+func TestDocxConverterNestedTables(t *testing.T) {
+	conv := NewHTMLToDocxConverter()
+	htmlContents := []string{`<html><body>
+		<p>Before</p>
+		<table><tr><td><table><tr><td>Inner</td></tr></table>After inner</td></tr></table>
+		<footer><table><tr><td>Footer cell</td></tr></table></footer>
+	</body></html>`}
+
+	if err := conv.Convert(htmlContents); err != nil {
+		t.Fatalf("Convert failed: %v", err)
+	}
+	var tables []*wml.CT_Tbl
+	for _, elts := range conv.doc.X().Body.EG_BlockLevelElts {
+		for _, c := range elts.EG_ContentBlockContent {
+			tables = append(tables, c.Tbl...)
+		}
+	}
+	if len(tables) != 1 {
+		t.Fatalf("expected one table in the body, got %d", len(tables))
+	}
+	outer := tables[0].EG_ContentRowContent[0].Tr[0].EG_ContentCellContent[0].Tc[0]
+	var kinds []string
+	for _, elts := range outer.EG_BlockLevelElts {
+		for _, c := range elts.EG_ContentBlockContent {
+			if len(c.Tbl) > 0 {
+				kinds = append(kinds, "table")
+			}
+			if len(c.P) > 0 {
+				kinds = append(kinds, "paragraph")
+			}
+		}
+	}
+	if want := []string{"paragraph", "table", "paragraph"}; !reflect.DeepEqual(kinds, want) {
+		t.Errorf("expected the cell to hold %v, got %v", want, kinds)
+	}
+
+	var footerTables int
+	for _, c := range conv.doc.Footers()[0].X().EG_ContentBlockContent {
+		footerTables += len(c.Tbl)
+	}
+	if footerTables != 1 {
+		t.Errorf("expected the footer table in the footer, got %d tables there", footerTables)
+	}
+}
+
+func TestDocxConverterHeaderFooterVariants(t *testing.T) {
+	conv := NewHTMLToDocxConverter()
+	htmlContents := []string{`<html><body>
+		<header data-page="first"><p>Title page header</p></header>
+		<header><p>Odd page header</p></header>
+		<header data-page="even"><p>Even page header</p></header>
+		<footer data-page="even"><p>Page <span data-field="page"></span></p></footer>
+		<p>Body content</p>
+	</body></html>`}
+
+	if err := conv.Convert(htmlContents); err != nil {
+		t.Fatalf("Convert failed: %v", err)
+	}
+	if conv.doc.BodySection().X().TitlePg == nil {
+		t.Error("expected titlePg to be set for a first-page header")
+	}
+	if conv.doc.Settings.X().EvenAndOddHeaders == nil {
+		t.Error("expected evenAndOddHeaders to be set for an even-page header")
+	}
+
+	tmpFile := filepath.Join(t.TempDir(), "variants.docx")
+	if err := conv.SaveToFile(tmpFile); err != nil {
+		t.Fatalf("SaveToFile failed: %v", err)
+	}
+}
+
+func TestDocxConverterSections(t *testing.T) {
+	conv := NewHTMLToDocxConverter()
+	sections := []Section{
+		{HTML: `<html><body><header><p>Portrait header</p></header><p>Portrait section</p></body></html>`},
+		{HTML: `<html><head><meta name="page-size" content="A4 landscape"></head><body><p>Landscape section</p></body></html>`,
+			Page: PageSetup{PageNumberStart: 1}},
+	}
+
+	if err := conv.ConvertSections(sections); err != nil {
+		t.Fatalf("ConvertSections failed: %v", err)
+	}
+	sect := conv.doc.BodySection().X()
+	if sect.PgSz == nil || sect.PgSz.OrientAttr != wml.ST_PageOrientationLandscape {
+		t.Error("expected the last section to be landscape")
+	}
+	if sect.PgNumType == nil || sect.PgNumType.StartAttr == nil || *sect.PgNumType.StartAttr != 1 {
+		t.Error("expected page numbering to restart in the last section")
+	}
+
+	tmpFile := filepath.Join(t.TempDir(), "sections.docx")
+	if err := conv.SaveToFile(tmpFile); err != nil {
+		t.Fatalf("SaveToFile failed: %v", err)
+	}
+}
+
+func TestDocxConverterPageNumberStartMeta(t *testing.T) {
+	conv := NewHTMLToDocxConverter()
+	htmlContents := []string{
+		`<html><body><p>Cover</p></body></html>`,
+		`<html><head><meta name="page-number-start" content="7"></head><body>
+			<p>Chapter</p>
+			<footer><p>Page <span data-field="page"></span></p></footer>
+		</body></html>`,
+	}
+
+	if err := conv.Convert(htmlContents); err != nil {
+		t.Fatalf("Convert failed: %v", err)
+	}
+	sect := conv.doc.BodySection().X()
+	if sect.PgNumType == nil || sect.PgNumType.StartAttr == nil || *sect.PgNumType.StartAttr != 7 {
+		t.Fatal("expected page numbering to start at 7 in the second section")
+	}
+	var paras []*wml.CT_P
+	for _, c := range conv.doc.Footers()[0].X().EG_ContentBlockContent {
+		paras = append(paras, c.P...)
+	}
+	if got := fieldCodes(paras); !reflect.DeepEqual(got, []string{document.FieldCurrentPage}) {
+		t.Errorf("expected the page number field in the footer, got %v", got)
+	}
+}
+
+func TestDocxConverterConvertTwice(t *testing.T) {
+	conv := NewHTMLToDocxConverter()
+	for _, content := range []string{
+		`<html><body><header><p>First header</p></header><p>First</p></body></html>`,
+		`<html><body><header><p>Second header</p></header><p>Second</p></body></html>`,
+	} {
+		if err := conv.Convert([]string{content}); err != nil {
+			t.Fatalf("Convert failed: %v", err)
+		}
+	}
+
+	var breaks []*wml.CT_SectPr
+	for _, p := range conv.doc.Paragraphs() {
+		if ppr := p.X().PPr; ppr != nil && ppr.SectPr != nil {
+			breaks = append(breaks, ppr.SectPr)
+		}
+	}
+	if len(breaks) != 1 {
+		t.Fatalf("expected a section break between the conversions, got %d", len(breaks))
+	}
+	for _, sect := range []*wml.CT_SectPr{breaks[0], conv.doc.BodySection().X()} {
+		if n := len(sect.EG_HdrFtrReferences); n != 1 {
+			t.Errorf("expected one header reference per section, got %d", n)
+		}
+	}
+}
+
+func TestDocxConverterOptions(t *testing.T) {
+	conv := NewHTMLToDocxConverter(Options{
+		Page:       PageSetup{Size: PageA4, Margins: &Margins{Top: 20, Right: 20, Bottom: 20, Left: 20}},
+		FontFamily: "Georgia",
+		FontSize:   12,
+	})
+	if err := conv.Convert([]string{`<html><body><p>Body content</p></body></html>`}); err != nil {
+		t.Fatalf("Convert failed: %v", err)
+	}
+	if sz := conv.doc.BodySection().X().PgSz; sz == nil || *sz.WAttr.ST_UnsignedDecimalNumber != 11906 {
+		t.Error("expected A4 page width of 11906 twips")
+	}
+	rpr := conv.doc.Styles.X().DocDefaults.RPrDefault.RPr
+	if rpr.RFonts == nil || *rpr.RFonts.AsciiAttr != "Georgia" {
+		t.Error("expected default font to be Georgia")
+	}
+	if rpr.Sz == nil || *rpr.Sz.ValAttr.ST_UnsignedDecimalNumber != 24 {
+		t.Error("expected default font size of 24 half-points")
+	}
+
+	tmpFile := filepath.Join(t.TempDir(), "options.docx")
+	if err := conv.SaveToFile(tmpFile); err != nil {
+		t.Fatalf("SaveToFile failed: %v", err)
+	}
+}
+
+func TestDocxConverterOptionsPageNumberStart(t *testing.T) {
+	conv := NewHTMLToDocxConverter(Options{Page: PageSetup{PageNumberStart: 5}})
+	if err := conv.Convert([]string{`<p>First</p>`, `<p>Second</p>`}); err != nil {
+		t.Fatalf("Convert failed: %v", err)
+	}
+
+	var starts []int64
+	for _, p := range conv.doc.Paragraphs() {
+		if ppr := p.X().PPr; ppr != nil && ppr.SectPr != nil && ppr.SectPr.PgNumType != nil {
+			starts = append(starts, *ppr.SectPr.PgNumType.StartAttr)
+		}
+	}
+	if !reflect.DeepEqual(starts, []int64{5}) {
+		t.Errorf("expected the first section to start at page 5, got %v", starts)
+	}
+	if conv.doc.BodySection().X().PgNumType != nil {
+		t.Error("expected the second section to continue the numbering")
+	}
+}
+
+func TestDocxConverterRTL(t *testing.T) {
+	conv := NewHTMLToDocxConverter()
+	htmlContents := []string{`<html><body>
+		<p>Left to right paragraph.</p>
+		<p>مرحبا بالعالم</p>
+		<div dir="rtl"><p>Explicit right to left</p></div>
+	</body></html>`}
+
+	if err := conv.Convert(htmlContents); err != nil {
+		t.Fatalf("Convert failed: %v", err)
+	}
+
+	tmpFile := filepath.Join(t.TempDir(), "rtl.docx")
+	if err := conv.SaveToFile(tmpFile); err != nil {
+		t.Fatalf("SaveToFile failed: %v", err)
+	}
+}
+
+func TestDocxConverterSetDirection(t *testing.T) {
+	conv := NewHTMLToDocxConverter()
+	p := conv.doc.AddParagraph()
+	if got := conv.setDirection(p, "שלום עולם", wml.ST_JcLeft); got != wml.ST_JcRight {
+		t.Errorf("expected right alignment for Hebrew text, got %v", got)
+	}
+	if p.Properties().X().Bidi == nil {
+		t.Error("expected bidi to be set for Hebrew text")
+	}
+
+	p = conv.doc.AddParagraph()
+	if got := conv.setDirection(p, "Hello", wml.ST_JcCenter); got != wml.ST_JcCenter {
+		t.Errorf("expected alignment to be kept for Latin text, got %v", got)
+	}
+	if p.Properties().X().Bidi != nil {
+		t.Error("expected no bidi for Latin text")
+	}
+}
+
+func TestDocxConverterLang(t *testing.T) {
+	conv := NewHTMLToDocxConverter()
+	htmlContents := []string{`<html lang="de-DE"><body>
+		<p>Guten Tag, <span lang="fr-FR">bonjour</span> und <b lang="ar">مرحبا</b>.</p>
+	</body></html>`}
+
+	if err := conv.Convert(htmlContents); err != nil {
+		t.Fatalf("Convert failed: %v", err)
+	}
+	lang := conv.doc.Styles.X().DocDefaults.RPrDefault.RPr.Lang
+	if lang == nil || lang.ValAttr == nil || *lang.ValAttr != "de-DE" {
+		t.Error("expected document default language de-DE")
+	}
+
+	tmpFile := filepath.Join(t.TempDir(), "lang.docx")
+	if err := conv.SaveToFile(tmpFile); err != nil {
+		t.Fatalf("SaveToFile failed: %v", err)
+	}
+}
+
+func TestDocxLanguage(t *testing.T) {
+	if l := docxLanguage("fr-CA"); l.ValAttr == nil || *l.ValAttr != "fr-CA" {
+		t.Error("expected fr-CA as the Latin language")
+	}
+	if l := docxLanguage("he"); l.BidiAttr == nil || *l.BidiAttr != "he" {
+		t.Error("expected he as the right-to-left language")
+	}
+	if l := docxLanguage("ja-JP"); l.EastAsiaAttr == nil || *l.EastAsiaAttr != "ja-JP" {
+		t.Error("expected ja-JP as the East Asian language")
+	}
+}
+
+func TestDocxConverterMetadata(t *testing.T) {
+	conv := NewHTMLToDocxConverter(Options{Metadata: Metadata{Author: "Jane Doe"}})
+	htmlContents := []string{`<html><head>
+		<title>Quarterly Report</title>
+		<meta name="author" content="Someone Else">
+		<meta name="keywords" content="finance, q3">
+	</head><body><p>Body text</p></body></html>`}
+
+	if err := conv.Convert(htmlContents); err != nil {
+		t.Fatalf("Convert failed: %v", err)
+	}
+	if got := conv.doc.CoreProperties.Title(); got != "Quarterly Report" {
+		t.Errorf("expected title from <title>, got %q", got)
+	}
+	if got := conv.doc.CoreProperties.Author(); got != "Jane Doe" {
+		t.Errorf("expected author from options, got %q", got)
+	}
+	for _, p := range conv.doc.Paragraphs() {
+		for _, r := range p.Runs() {
+			if r.Text() == "Quarterly Report" {
+				t.Error("expected <title> text not to appear in the body")
+			}
+		}
+	}
+
+	tmpFile := filepath.Join(t.TempDir(), "metadata.docx")
+	if err := conv.SaveToFile(tmpFile); err != nil {
+		t.Fatalf("SaveToFile failed: %v", err)
+	}
+}
+
+func TestDocxConverterBytes(t *testing.T) {
+	conv := NewHTMLToDocxConverter()
+	if err := conv.Convert([]string{`<html><body><p>Streamed</p></body></html>`}); err != nil {
+		t.Fatalf("Convert failed: %v", err)
+	}
+
+	data, err := conv.Bytes()
+	if err != nil {
+		t.Fatalf("Bytes failed: %v", err)
+	}
+	if !bytes.HasPrefix(data, []byte("PK")) {
+		t.Error("expected a zip archive")
+	}
+	var buf bytes.Buffer
+	if _, err := conv.WriteTo(&buf); err != nil {
+		t.Fatalf("WriteTo failed: %v", err)
+	}
+	if buf.Len() == 0 {
+		t.Error("expected WriteTo to write the document")
+	}
+}
+
+func TestDocxConverterReader(t *testing.T) {
+	conv := NewHTMLToDocxConverter()
+	input := strings.NewReader("<html><head><meta charset=\"windows-1252\"></head><body><p>Caf\xe9</p></body></html>")
+
+	if err := conv.ConvertReader(input, ""); err != nil {
+		t.Fatalf("ConvertReader failed: %v", err)
+	}
+
+	tmpFile := filepath.Join(t.TempDir(), "reader.docx")
+	if err := conv.SaveToFile(tmpFile); err != nil {
+		t.Fatalf("SaveToFile failed: %v", err)
+	}
+}
@@ -1,7 +1,9 @@
 package converter
 
 import (
+	"context"
 	"fmt"
+	"io"
 	"os"
 	"strings"
 
@@ -9,227 +11,256 @@ import (
 )
 
 // HTMLToMarkdownConverter converts HTML content to Markdown format.
+// It builds a single document, which each conversion adds to, and is not
+// safe for concurrent use; an Engine creates a fresh converter per conversion.
 type HTMLToMarkdownConverter struct {
 	markdown  strings.Builder
 	listDepth int
+	keepLang  bool                       // keep <span lang> elements as inline HTML
+	guard     guard                      // cancellation and resource limits
+	classes   map[string]Style           // formatting by class name, see Options.ClassStyles
+	handlers  map[string]MarkdownHandler // custom element handlers, see Handle
+	bypass    *Element                   // element a handler delegated to the built-in handling
 }
 
 // NewHTMLToMarkdownConverter creates a new Markdown converter.
-func NewHTMLToMarkdownConverter() *HTMLToMarkdownConverter {
-	return &HTMLToMarkdownConverter{}
+func NewHTMLToMarkdownConverter(opts ...Options) *HTMLToMarkdownConverter {
+	o := firstOptions(opts)
+	return &HTMLToMarkdownConverter{
+		keepLang: o.KeepLangSpans,
+		guard:    guard{limits: o.Limits, strict: o.Strict},
+		classes:  o.ClassStyles,
+	}
 }
 
-// Convert parses and converts multiple HTML strings to a Markdown string.
+// Convert parses and converts multiple HTML strings to a Markdown string. The
+// string holds the output of earlier conversions too, see Engine.
 func (c *HTMLToMarkdownConverter) Convert(htmlContents []string) (string, error) {
-	for i, content := range htmlContents {
-		content = UnescapeUnicodeHTML(content)
-		root, err := html.Parse(strings.NewReader(content))
-		if err != nil {
-			return "", fmt.Errorf("failed to parse HTML: %w", err)
+	return c.ConvertContext(context.Background(), htmlContents)
+}
+
+// ConvertContext is like Convert but stops with an error once ctx is done or
+// a limit set in Options.Limits is exceeded.
+func (c *HTMLToMarkdownConverter) ConvertContext(ctx context.Context, htmlContents []string) (string, error) {
+	if err := c.ConvertSectionsContext(ctx, HTMLSections(htmlContents)); err != nil {
+		return "", err
+	}
+	return c.markdown.String(), nil
+}
+
+// ConvertReader reads one HTML document from r, decoding it to UTF-8 as
+// described for ReadHTML, and converts it like Convert.
+func (c *HTMLToMarkdownConverter) ConvertReader(r io.Reader, contentType string) (string, error) {
+	content, err := ReadHTML(r, contentType)
+	if err != nil {
+		return "", err
+	}
+	return c.Convert([]string{content})
+}
+
+// ConvertSections converts each HTML input to Markdown, separating sections
+// with a horizontal rule. Page setup does not apply to Markdown and is
+// ignored.
+func (c *HTMLToMarkdownConverter) ConvertSections(sections []Section) error {
+	return c.ConvertSectionsContext(context.Background(), sections)
+}
+
+// ConvertSectionsContext is like ConvertSections but stops with an error once
+// ctx is done or a limit set in Options.Limits is exceeded.
+func (c *HTMLToMarkdownConverter) ConvertSectionsContext(ctx context.Context, sections []Section) error {
+	docs, err := parseSections(ctx, sections, c.guard.limits, c.classes)
+	if err != nil {
+		return err
+	}
+	return c.convertDocuments(ctx, docs)
+}
+
+// convertDocuments renders documents already built from HTML, separated by
+// horizontal rules.
+func (c *HTMLToMarkdownConverter) convertDocuments(ctx context.Context, docs []*Document) error {
+	c.guard.start(ctx)
+	for i, doc := range docs {
+		c.walkDocumentMD(doc)
+		if c.guard.err != nil {
+			return c.guard.err
 		}
-		c.walkMD(root)
-		if i < len(htmlContents)-1 {
+		if i < len(docs)-1 {
 			c.markdown.WriteString("\n\n---\n\n")
 		}
 	}
-	return c.markdown.String(), nil
+	return nil
+}
+
+// walkDocumentMD renders a document with its page headers before the body
+// and its page footers after it. Markdown has no pages, so the regions of
+// every page variant are rendered; headers and footers inside the content
+// are rendered in place.
+func (c *HTMLToMarkdownConverter) walkDocumentMD(doc *Document) {
+	regions := func(byVariant map[string][]*Element) {
+		for _, variant := range pageVariants {
+			for _, e := range byVariant[variant] {
+				if isPageRegion(e) {
+					c.walkMD(e)
+				}
+			}
+		}
+	}
+	regions(doc.Headers)
+	c.walkMD(doc.Body)
+	regions(doc.Footers)
+}
+
+// output returns the converted Markdown with blank line runs collapsed and
+// surrounding whitespace trimmed.
+func (c *HTMLToMarkdownConverter) output() string {
+	markdown := c.markdown.String()
+	for strings.Contains(markdown, "\n\n\n") {
+		markdown = strings.ReplaceAll(markdown, "\n\n\n", "\n\n")
+	}
+	return strings.TrimSpace(markdown)
+}
+
+// Warnings returns the warnings about unsupported content collected by all
+// conversions so far.
+func (c *HTMLToMarkdownConverter) Warnings() []Warning {
+	return append([]Warning(nil), c.guard.warnings...)
+}
+
+// WriteTo writes the converted Markdown to w.
+func (c *HTMLToMarkdownConverter) WriteTo(w io.Writer) (int64, error) {
+	n, err := io.WriteString(w, c.output())
+	return int64(n), err
+}
+
+// Bytes returns the converted Markdown.
+func (c *HTMLToMarkdownConverter) Bytes() ([]byte, error) {
+	return []byte(c.output()), nil
+}
+
+// SaveToFile saves the converted Markdown to a file.
+func (c *HTMLToMarkdownConverter) SaveToFile(filename string) error {
+	return os.WriteFile(filename, []byte(c.output()), 0644)
 }
 
-func (c *HTMLToMarkdownConverter) walkMD(n *html.Node) {
-	if n.Type == html.TextNode {
-		text := n.Data
-		if strings.TrimSpace(text) != "" {
-			c.markdown.WriteString(text)
+// walkMD renders an element of the document model as Markdown.
+func (c *HTMLToMarkdownConverter) walkMD(e *Element) {
+	if !c.guard.enter() {
+		return
+	}
+	if e.Kind == KindText {
+		if strings.TrimSpace(e.Text) != "" {
+			c.markdown.WriteString(e.Text)
 		}
 		return
 	}
 
-	if n.Type == html.ElementNode {
-		switch EffectiveNodeType(n) {
-		case "h1":
-			c.markdown.WriteString("\n# ")
-			c.processChildrenMD(n)
-			c.markdown.WriteString("\n\n")
-			return
-		case "h2":
-			c.markdown.WriteString("\n## ")
-			c.processChildrenMD(n)
-			c.markdown.WriteString("\n\n")
-			return
-		case "h3":
-			c.markdown.WriteString("\n### ")
-			c.processChildrenMD(n)
-			c.markdown.WriteString("\n\n")
-			return
-		case "h4":
-			c.markdown.WriteString("\n#### ")
-			c.processChildrenMD(n)
-			c.markdown.WriteString("\n\n")
-			return
-		case "h5":
-			c.markdown.WriteString("\n##### ")
-			c.processChildrenMD(n)
-			c.markdown.WriteString("\n\n")
-			return
-		case "h6":
-			c.markdown.WriteString("\n###### ")
-			c.processChildrenMD(n)
-			c.markdown.WriteString("\n\n")
-			return
-		case "p":
-			c.processChildrenMD(n)
-			c.markdown.WriteString("\n\n")
-			return
-		case "br":
-			c.markdown.WriteString("  \n")
-			return
-		case "hr":
-			c.markdown.WriteString("\n---\n\n")
-			return
-		case "b", "strong":
-			c.markdown.WriteString("**")
-			c.processChildrenMD(n)
-			c.markdown.WriteString("**")
-			return
-		case "i", "em":
-			c.markdown.WriteString("*")
-			c.processChildrenMD(n)
-			c.markdown.WriteString("*")
-			return
-		case "u":
-			c.markdown.WriteString("<u>")
-			c.processChildrenMD(n)
-			c.markdown.WriteString("</u>")
-			return
-		case "ul":
-			c.markdown.WriteString("\n")
-			c.processListMD(n, false)
-			c.markdown.WriteString("\n")
-			return
-		case "ol":
-			c.markdown.WriteString("\n")
-			c.processListMD(n, true)
-			c.markdown.WriteString("\n")
-			return
-		case "table":
-			c.processTableMD(n)
-			return
-		case "a":
-			href := GetAttrValue(n.Attr, "href")
-			c.markdown.WriteString("[")
-			c.processChildrenMD(n)
-			c.markdown.WriteString("](" + href + ")")
-			return
-		case "img":
-			src := GetAttrValue(n.Attr, "src")
-			alt := GetAttrValue(n.Attr, "alt")
-			c.markdown.WriteString("![" + alt + "](" + src + ")")
-			return
-		case "code":
-			c.markdown.WriteString("`")
-			c.processChildrenMD(n)
-			c.markdown.WriteString("`")
-			return
-		case "pre":
-			c.markdown.WriteString("\n```\n")
-			c.processChildrenMD(n)
-			c.markdown.WriteString("\n```\n\n")
-			return
-		case "blockquote":
-			c.markdown.WriteString("\n> ")
-			c.processChildrenMD(n)
-			c.markdown.WriteString("\n\n")
-			return
-		case "div", "span":
-			// transparently process children
-			c.processChildrenMD(n)
-			return
+	if h := c.handlers[e.Type]; h != nil && c.bypass != e {
+		if err := h(e.Source, &MarkdownContext{Builder: &c.markdown, Element: e, c: c}); err != nil {
+			c.guard.fail(handlerError(e.Type, err))
 		}
+		return
 	}
+	c.bypass = nil
 
-	c.processChildrenMD(n)
+	switch e.Kind {
+	case KindUnsupported:
+		c.guard.warn(e.Source, e.Reason)
+	case KindHeading:
+		c.markdown.WriteString("\n" + strings.Repeat("#", e.Level) + " ")
+		c.processChildrenMD(e)
+		c.markdown.WriteString("\n\n")
+	case KindParagraph:
+		c.processChildrenMD(e)
+		c.markdown.WriteString("\n\n")
+	case KindBreak:
+		c.markdown.WriteString("  \n")
+	case KindRule:
+		c.markdown.WriteString("\n---\n\n")
+	case KindList:
+		c.markdown.WriteString("\n")
+		c.processListMD(e)
+		c.markdown.WriteString("\n")
+	case KindTable:
+		c.processTableMD(e)
+	case KindLink:
+		c.markdown.WriteString("[")
+		c.processChildrenMD(e)
+		c.markdown.WriteString("](" + e.Href + ")")
+	case KindImage:
+		c.markdown.WriteString("![" + e.Alt + "](" + e.Src + ")")
+	case KindPre:
+		c.markdown.WriteString("\n```\n")
+		c.processChildrenMD(e)
+		c.markdown.WriteString("\n```\n\n")
+	case KindQuote:
+		c.markdown.WriteString("\n> ")
+		c.processChildrenMD(e)
+		c.markdown.WriteString("\n\n")
+	case KindSpan:
+		c.processSpanMD(e)
+	default:
+		c.processChildrenMD(e)
+	}
 }
 
-func (c *HTMLToMarkdownConverter) processChildrenMD(n *html.Node) {
-	for ch := n.FirstChild; ch != nil; ch = ch.NextSibling {
-		c.walkMD(ch)
+// processSpanMD renders inline content with the markers for the formatting
+// the element itself applies.
+func (c *HTMLToMarkdownConverter) processSpanMD(e *Element) {
+	var open, close string
+	if e.Style.Code {
+		open, close = open+"`", "`"+close
+	}
+	if e.Style.Bold {
+		open, close = open+"**", "**"+close
+	}
+	if e.Style.Italic {
+		open, close = open+"*", "*"+close
 	}
+	if e.Style.Underline {
+		open, close = open+"<u>", "</u>"+close
+	}
+	if c.keepLang && e.Type == "span" && e.Lang != "" {
+		open, close = open+`<span lang="`+html.EscapeString(e.Lang)+`">`, "</span>"+close
+	}
+	c.markdown.WriteString(open)
+	c.processChildrenMD(e)
+	c.markdown.WriteString(close)
 }
 
-func (c *HTMLToMarkdownConverter) processListMD(n *html.Node, ordered bool) {
-	index := 1
-	for li := n.FirstChild; li != nil; li = li.NextSibling {
-		if li.Type == html.ElementNode && EffectiveNodeType(li) == "li" {
-			indent := strings.Repeat("  ", c.listDepth)
-			if ordered {
-				c.markdown.WriteString(fmt.Sprintf("%s%d. ", indent, index))
-				index++
-			} else {
-				c.markdown.WriteString(indent + "- ")
-			}
-			c.listDepth++
-			c.processChildrenMD(li)
-			c.listDepth--
-			c.markdown.WriteString("\n")
-		}
+func (c *HTMLToMarkdownConverter) processChildrenMD(e *Element) {
+	for _, ch := range e.Children {
+		c.walkMD(ch)
 	}
 }
 
-func (c *HTMLToMarkdownConverter) processTableMD(n *html.Node) {
-	c.markdown.WriteString("\n")
-
-	var rows [][]*html.Node
-	var isHeader []bool
-
-	var collectRows func(*html.Node)
-	collectRows = func(node *html.Node) {
-		for ch := node.FirstChild; ch != nil; ch = ch.NextSibling {
-			if ch.Type == html.ElementNode {
-				if EffectiveNodeType(ch) == "tr" {
-					var cells []*html.Node
-					header := false
-
-					var collectCells func(*html.Node)
-					collectCells = func(cellNode *html.Node) {
-						for cell := cellNode.FirstChild; cell != nil; cell = cell.NextSibling {
-							if cell.Type == html.ElementNode {
-								cellType := EffectiveNodeType(cell)
-								if cellType == "td" || cellType == "th" {
-									cells = append(cells, cell)
-									if cellType == "th" {
-										header = true
-									}
-								} else if cellType == "div" || cellType == "span" {
-									collectCells(cell)
-								}
-							}
-						}
-					}
-					collectCells(ch)
-
-					if len(cells) > 0 {
-						rows = append(rows, cells)
-						isHeader = append(isHeader, header)
-					}
-				} else {
-					collectRows(ch)
-				}
-			}
+func (c *HTMLToMarkdownConverter) processListMD(e *Element) {
+	for i, li := range e.Children {
+		indent := strings.Repeat("  ", c.listDepth)
+		if e.Ordered {
+			c.markdown.WriteString(fmt.Sprintf("%s%d. ", indent, i+1))
+		} else {
+			c.markdown.WriteString(indent + "- ")
 		}
+		c.listDepth++
+		c.walkMD(li)
+		c.listDepth--
+		c.markdown.WriteString("\n")
 	}
-	collectRows(n)
+}
 
-	for i, row := range rows {
+func (c *HTMLToMarkdownConverter) processTableMD(e *Element) {
+	c.markdown.WriteString("\n")
+	for i, row := range e.Children {
 		c.markdown.WriteString("| ")
-		for _, cell := range row {
+		for _, cell := range row.Children {
 			c.processChildrenMD(cell)
 			c.markdown.WriteString(" | ")
 		}
 		c.markdown.WriteString("\n")
 
-		if i == 0 || (i < len(isHeader) && isHeader[i]) {
+		if i == 0 || row.Header {
 			c.markdown.WriteString("|")
-			for range row {
+			for range row.Children {
 				c.markdown.WriteString(" --- |")
 			}
 			c.markdown.WriteString("\n")
@@ -241,13 +272,8 @@ func (c *HTMLToMarkdownConverter) processTableMD(n *html.Node) {
 // ConvertHTMLToMarkdown is a convenience function that converts HTML to Markdown and saves it to a file.
 func ConvertHTMLToMarkdown(htmlContents []string, outputPath string) error {
 	conv := NewHTMLToMarkdownConverter()
-	markdown, err := conv.Convert(htmlContents)
-	if err != nil {
+	if _, err := conv.Convert(htmlContents); err != nil {
 		return fmt.Errorf("failed to convert HTML to Markdown: %w", err)
 	}
-
-	markdown = strings.ReplaceAll(markdown, "\n\n\n", "\n\n")
-	markdown = strings.TrimSpace(markdown)
-
-	return os.WriteFile(outputPath, []byte(markdown), 0644)
+	return conv.SaveToFile(outputPath)
 }
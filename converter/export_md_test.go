@@ -242,3 +242,97 @@ func TestConvertHTMLToMarkdownFile(t *testing.T) {
 		t.Error("output contains triple newlines, cleanup failed")
 	}
 }
+
+func TestMarkdownConverterLangSpans(t *testing.T) {
+	htmlContents := []string{`<html lang="en"><body>
+		<p>Say <span lang="fr">bonjour</span> to everyone.</p>
+	</body></html>`}
+
+	md, err := NewHTMLToMarkdownConverter().Convert(htmlContents)
+	if err != nil {
+		t.Fatalf("Convert failed: %v", err)
+	}
+	if strings.Contains(md, "<span") {
+		t.Errorf("expected language spans to be dropped by default, got: %s", md)
+	}
+
+	md, err = NewHTMLToMarkdownConverter(Options{KeepLangSpans: true}).Convert(htmlContents)
+	if err != nil {
+		t.Fatalf("Convert failed: %v", err)
+	}
+	if !strings.Contains(md, `<span lang="fr">bonjour</span>`) {
+		t.Errorf("expected language span to be kept, got: %s", md)
+	}
+}
+
+func TestMarkdownConverterBytes(t *testing.T) {
+	conv := NewHTMLToMarkdownConverter()
+	if _, err := conv.Convert([]string{`<html><body><h1>Title</h1></body></html>`}); err != nil {
+		t.Fatalf("Convert failed: %v", err)
+	}
+
+	data, err := conv.Bytes()
+	if err != nil {
+		t.Fatalf("Bytes failed: %v", err)
+	}
+	if string(data) != "# Title" {
+		t.Errorf("expected trimmed Markdown, got %q", data)
+	}
+	var sb strings.Builder
+	if _, err := conv.WriteTo(&sb); err != nil {
+		t.Fatalf("WriteTo failed: %v", err)
+	}
+	if sb.String() != string(data) {
+		t.Errorf("WriteTo wrote %q, want %q", sb.String(), data)
+	}
+}
+
+func TestMarkdownConverterReader(t *testing.T) {
+	conv := NewHTMLToMarkdownConverter()
+	input := strings.NewReader("<html><body><h1>Caf\xe9</h1></body></html>")
+
+	md, err := conv.ConvertReader(input, "text/html; charset=windows-1252")
+	if err != nil {
+		t.Fatalf("ConvertReader failed: %v", err)
+	}
+	if !strings.Contains(md, "# Café") {
+		t.Errorf("expected decoded heading, got: %s", md)
+	}
+}
+
+func TestMarkdownConverterDocumentModel(t *testing.T) {
+	conv := NewHTMLToMarkdownConverter()
+	md, err := conv.Convert([]string{`<html><head><title>Hidden</title><style>p{}</style></head><body>
+		<footer>Footer text</footer>
+		<header>Header text</header>
+		<div align="right">Loose text</div><p>Next para</p>
+	</body></html>`})
+	if err != nil {
+		t.Fatalf("Convert failed: %v", err)
+	}
+	want := "Header text\n\nLoose text\n\nNext para\n\nFooter text"
+	if md = strings.TrimSpace(md); md != want {
+		t.Errorf("expected %q, got %q", want, md)
+	}
+}
+
+func TestMarkdownConverterRegions(t *testing.T) {
+	conv := NewHTMLToMarkdownConverter()
+	if _, err := conv.Convert([]string{`<html><body>
+		<header data-page="first">Title page</header>
+		<header>Running header</header>
+		<article><header><h2>Article title</h2></header><p>Article body</p><footer>Posted today</footer></article>
+		<p>Closing</p>
+		<footer data-page="even">Even footer</footer>
+	</body></html>`}); err != nil {
+		t.Fatalf("Convert failed: %v", err)
+	}
+	out, err := conv.Bytes()
+	if err != nil {
+		t.Fatalf("Bytes failed: %v", err)
+	}
+	want := "Running header\n\nTitle page\n\n## Article title\n\nArticle body\n\nPosted today\n\nClosing\n\nEven footer"
+	if md := string(out); md != want {
+		t.Errorf("expected %q, got %q", want, md)
+	}
+}
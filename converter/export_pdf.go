@@ -1,162 +1,666 @@
 package converter
 
 import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"errors"
 	"fmt"
+	"hash/fnv"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
 	"strconv"
 	"strings"
 
 	"github.com/jung-kurt/gofpdf"
-	"golang.org/x/net/html"
+	"golang.org/x/text/unicode/bidi"
 )
 
+// ErrPDFWritten is returned by a conversion on an HTMLToPDFConverter whose
+// document has already been written out.
+var ErrPDFWritten = errors.New("PDF document already written")
+
 // HTMLToPDFConverter converts HTML content to PDF format using gofpdf.
+// It builds a single document, which each conversion adds to until the first
+// call to Bytes, WriteTo or SaveToFile closes it; later conversions fail with
+// ErrPDFWritten. It is not safe for concurrent use; an Engine creates a fresh
+// converter per conversion.
 type HTMLToPDFConverter struct {
 	pdf        *gofpdf.Fpdf
 	fontStyle  string  // current style: combination of B, I, U
 	fontSize   float64 // current font size in pt
 	fontFamily string
-	tr         func(string) string // UTF-8 translator
-	centered   bool                // true when inside a <center> tag
+	tr         func(string) string // UTF-8 to cp1252 translator for core fonts
+	utf8       bool                // the current font is an embedded UTF-8 font
+	fonts      map[string]*pdfFont // embedded font families by lowercase name
+	fallbacks  []string            // families tried for characters the current font lacks
+	align      string              // "C", "R" or "L" inside an aligned block, "" for plain flow
+	dir        string              // "rtl" or "ltr" from a dir attribute, "" to detect from the text
+	rtl        bool                // the current block reads right to left
+	pending    []pdfRun            // aligned text waiting to be laid out as lines
+	lang       string              // document language from <html lang>, written as the catalog /Lang
+	meta       Metadata            // document properties, from Options and the HTML
+	out        []byte              // rendered document, see Bytes
+	guard      guard               // cancellation and resource limits
+	classes    map[string]Style    // formatting by class name, see Options.ClassStyles
+	noFiles    bool                // images are only read from resources, see Options.NoLocalImages
+	resources  []string            // directories to find images in, see Options.ResourceDirs
+	fontErr    error               // failure to embed Options.Fonts, reported by the first conversion
+	baseName   string              // default font family requested in Options
+	baseFamily string              // default font family in use
+	baseSize   float64             // default font size
+	pre        bool                // inside preformatted text
+	page       PageSetup           // page setup of the latest section
+	start      int                 // Options.Page.PageNumberStart, until the first section
+	section    *pdfSection         // section the current page belongs to
+	next       *pdfSection         // section that takes over on the next page
+
+	handlers map[string]PDFHandler // custom element handlers, see Handle
+	bypass   *Element              // element a handler delegated to the built-in handling
+}
+
+// pdfSection holds the page setup, headers and footers of one HTML input.
+type pdfSection struct {
+	page      PageSetup
+	headers   map[string]*pdfRegion // keyed by PageVariant
+	footers   map[string]*pdfRegion // keyed by PageVariant
+	titlePage bool                  // the first page uses the "first" variants
+	evenOdd   bool                  // even pages use the "even" variants
+	firstPage int                   // page number the section starts on
+	offset    int                   // added to the page number to get the displayed one
+}
+
+// pdfRegion is one variant of a page header or footer.
+type pdfRegion struct {
+	elements []*Element
+	height   float64 // measured height, used for footers
+}
+
+// pdfRun is a piece of aligned text together with the style it was written in.
+type pdfRun struct {
+	text    string
+	family  string
+	style   string
+	size    float64
+	r, g, b int
+}
+
+// pdfPagesAlias is replaced by gofpdf with the total page count on output.
+const pdfPagesAlias = "{nb}"
+
+// pdfHeaderGap is the space left between the page header or footer and the body.
+const pdfHeaderGap = 3
+
+// pdfDefaultPage is the page setup used when none is given: A4 with 15mm
+// margins, and headers and footers 10mm from the page edge.
+var pdfDefaultPage = PageSetup{
+	Size:           PageA4,
+	Orientation:    Portrait,
+	Margins:        &Margins{Top: 15, Right: 15, Bottom: 15, Left: 15},
+	HeaderDistance: 10,
+	FooterDistance: 10,
 }
 
-// NewHTMLToPDFConverter creates a new PDF converter with A4 page and default margins.
-func NewHTMLToPDFConverter() *HTMLToPDFConverter {
+// NewHTMLToPDFConverter creates a new PDF converter. Without options it uses
+// A4 pages with 15mm margins and Arial 11pt. The fonts in Options.Fonts are
+// embedded; if one fails to load, converting returns the error.
+func NewHTMLToPDFConverter(opts ...Options) *HTMLToPDFConverter {
+	o := firstOptions(opts)
+	page := o.Page.Merge(pdfDefaultPage)
+	size := o.FontSize
+	if size <= 0 {
+		size = 11
+	}
+
 	pdf := gofpdf.New("P", "mm", "A4", "")
-	pdf.SetMargins(15, 15, 15)
-	pdf.SetAutoPageBreak(true, 15)
-	pdf.AddPage()
-	pdf.SetFont("Arial", "", 11)
+	pdf.SetMargins(page.Margins.Left, page.Margins.Top, page.Margins.Right)
+	pdf.SetAutoPageBreak(true, page.Margins.Bottom)
+	pdf.AliasNbPages(pdfPagesAlias)
 	tr := pdf.UnicodeTranslatorFromDescriptor("")
-	return &HTMLToPDFConverter{
-		pdf:        pdf,
-		fontStyle:  "",
-		fontSize:   11,
-		fontFamily: "Arial",
-		tr:         tr,
+	c := &HTMLToPDFConverter{
+		pdf:       pdf,
+		fontStyle: "",
+		fontSize:  size,
+		tr:        tr,
+		fonts:     map[string]*pdfFont{},
+		baseName:  o.FontFamily,
+		meta:      o.Metadata,
+		guard:     guard{limits: o.Limits, strict: o.Strict},
+		classes:   o.ClassStyles,
+		noFiles:   o.NoLocalImages,
+		resources: o.ResourceDirs,
+		baseSize:  size,
+		page:      page,
+		start:     o.Page.PageNumberStart,
+		section:   newPDFSection(page),
+	}
+	c.resolveBaseFont()
+	c.fontFamily = c.baseFamily
+	c.applyFont()
+	pdf.SetHeaderFunc(c.startPagePDF)
+	pdf.SetFooterFunc(c.renderFooterPDF)
+
+	families := make([]string, 0, len(o.Fonts))
+	for family := range o.Fonts {
+		families = append(families, family)
 	}
+	sort.Strings(families)
+	for _, family := range families {
+		if err := c.RegisterFont(family, o.Fonts[family]); err != nil && c.fontErr == nil {
+			c.fontErr = err
+		}
+	}
+	return c
 }
 
-// Convert parses and converts multiple HTML strings to PDF content.
+// newPDFSection returns a section with the given page setup and no headers or footers.
+func newPDFSection(page PageSetup) *pdfSection {
+	return &pdfSection{
+		page:      page,
+		headers:   map[string]*pdfRegion{},
+		footers:   map[string]*pdfRegion{},
+		firstPage: 1,
+	}
+}
+
+// Convert parses and converts multiple HTML strings to PDF content. Each
+// string starts on a new page, see ConvertSections.
 func (c *HTMLToPDFConverter) Convert(htmlContents []string) error {
-	for i, content := range htmlContents {
-		content = UnescapeUnicodeHTML(content)
-		root, err := html.Parse(strings.NewReader(content))
-		if err != nil {
-			return fmt.Errorf("failed to parse HTML index %d: %w", i, err)
+	return c.ConvertSections(HTMLSections(htmlContents))
+}
+
+// ConvertContext is like Convert but stops with an error once ctx is done.
+func (c *HTMLToPDFConverter) ConvertContext(ctx context.Context, htmlContents []string) error {
+	return c.ConvertSectionsContext(ctx, HTMLSections(htmlContents))
+}
+
+// ConvertReader reads one HTML document from r, decoding it to UTF-8 as
+// described for ReadHTML, and converts it like Convert.
+func (c *HTMLToPDFConverter) ConvertReader(r io.Reader, contentType string) error {
+	content, err := ReadHTML(r, contentType)
+	if err != nil {
+		return err
+	}
+	return c.Convert([]string{content})
+}
+
+// ConvertSections converts each HTML input into its own run of pages with its
+// own page setup, headers and footers. A section without headers or footers
+// keeps those of the previous one.
+func (c *HTMLToPDFConverter) ConvertSections(sections []Section) error {
+	return c.ConvertSectionsContext(context.Background(), sections)
+}
+
+// ConvertSectionsContext is like ConvertSections but stops with an error once
+// ctx is done or a limit set in Options.Limits is exceeded.
+func (c *HTMLToPDFConverter) ConvertSectionsContext(ctx context.Context, sections []Section) error {
+	docs, err := parseSections(ctx, sections, c.guard.limits, c.classes)
+	if err != nil {
+		return err
+	}
+	return c.convertDocuments(ctx, docs)
+}
+
+// convertDocuments renders documents already built from HTML, each starting
+// on a new page.
+func (c *HTMLToPDFConverter) convertDocuments(ctx context.Context, docs []*Document) error {
+	if c.out != nil {
+		return ErrPDFWritten
+	}
+	if c.fontErr != nil {
+		return c.fontErr
+	}
+	c.guard.start(ctx)
+	for _, doc := range docs {
+		if c.lang == "" {
+			c.lang = doc.Lang
 		}
-		c.walkPDF(root)
-		if i < len(htmlContents)-1 {
-			c.pdf.AddPage()
+		c.meta = c.meta.Merge(doc.Metadata)
+		page := doc.Page.Merge(c.page)
+		if page.PageNumberStart == 0 {
+			page.PageNumberStart = c.start
 		}
+		c.start = 0
+		c.startSectionPDF(doc, page)
+		c.walkPDF(doc.Body)
+		if c.guard.err != nil {
+			return c.guard.err
+		}
+		c.flushAligned()
 	}
+	c.applyMetadata()
 	return nil
 }
 
+// applyMetadata records the document metadata in the PDF Info dictionary. A
+// description stands in for a missing subject, which PDF has no separate
+// field for.
+func (c *HTMLToPDFConverter) applyMetadata() {
+	subject := c.meta.Subject
+	if subject == "" {
+		subject = c.meta.Description
+	}
+	if c.meta.Title != "" {
+		c.pdf.SetTitle(c.meta.Title, true)
+	}
+	if c.meta.Author != "" {
+		c.pdf.SetAuthor(c.meta.Author, true)
+	}
+	if subject != "" {
+		c.pdf.SetSubject(subject, true)
+	}
+	if len(c.meta.Keywords) > 0 {
+		c.pdf.SetKeywords(strings.Join(c.meta.Keywords, ", "), true)
+	}
+	if c.meta.Creator != "" {
+		c.pdf.SetCreator(c.meta.Creator, true)
+	}
+}
+
 func (c *HTMLToPDFConverter) lineHeight() float64 {
 	return c.fontSize * 0.4
 }
 
 func (c *HTMLToPDFConverter) applyFont() {
-	c.pdf.SetFont(c.fontFamily, c.fontStyle, c.fontSize)
+	c.setFont(c.fontFamily, c.fontStyle, c.fontSize)
 }
 
+// writeText writes inline text, switching to fallback fonts for characters
+// the current font has no glyph for.
 func (c *HTMLToPDFConverter) writeText(text string) {
+	family := c.fontFamily
+	for _, run := range c.fontRuns(text) {
+		c.fontFamily = run.family
+		c.writeRun(run.text)
+	}
+	if c.fontFamily != family {
+		c.fontFamily = family
+		c.applyFont()
+	}
+}
+
+// writeRun writes text in the current font, or queues it for line layout
+// inside an aligned block.
+func (c *HTMLToPDFConverter) writeRun(text string) {
+	if c.align != "" {
+		r, g, b := c.pdf.GetTextColor()
+		c.pending = append(c.pending, pdfRun{text: text, family: c.fontFamily, style: c.fontStyle, size: c.fontSize, r: r, g: g, b: b})
+		return
+	}
 	c.applyFont()
-	if c.centered {
-		pageW, _ := c.pdf.GetPageSize()
-		lMargin, _, rMargin, _ := c.pdf.GetMargins()
-		usableW := pageW - lMargin - rMargin
-		c.pdf.SetX(lMargin)
-		c.pdf.CellFormat(usableW, c.lineHeight(), c.tr(text), "", 1, "C", false, 0, "")
-	} else {
-		c.pdf.Write(c.lineHeight(), c.tr(text))
+	if HasRTL(text) {
+		text = visualText(text, false)
 	}
+	c.pdf.Write(c.lineHeight(), c.encodeText(text))
 }
 
-func (c *HTMLToPDFConverter) walkPDF(n *html.Node) {
-	if n.Type == html.TextNode {
-		text := n.Data
-		if strings.TrimSpace(text) == "" {
-			return
+// flushAligned lays out pending aligned text as wrapped lines, positioning
+// each line according to the alignment that was active when it was written.
+func (c *HTMLToPDFConverter) flushAligned() {
+	runs, align := c.pending, c.align
+	c.pending = nil
+	if len(runs) == 0 {
+		return
+	}
+	if align == "" {
+		align = "C"
+	}
+
+	pageW, _ := c.pdf.GetPageSize()
+	lMargin, _, rMargin, _ := c.pdf.GetMargins()
+	usableW := pageW - lMargin - rMargin
+
+	var lines [][]pdfSegment
+	var line []pdfSegment
+	lineW := 0.0
+	for _, run := range runs {
+		c.setFont(run.family, run.style, run.size)
+		for _, word := range splitWords(run.text) {
+			if len(line) == 0 {
+				word = strings.TrimLeft(word, " ")
+				if word == "" {
+					continue
+				}
+			}
+			w := c.textWidth(word)
+			if len(line) > 0 && lineW+c.textWidth(strings.TrimRight(word, " ")) > usableW {
+				lines = append(lines, line)
+				line, lineW = nil, 0
+				word = strings.TrimLeft(word, " ")
+				w = c.textWidth(word)
+			}
+			seg := pdfSegment{pdfRun: run, w: w}
+			seg.text = word
+			line = append(line, seg)
+			lineW += w
 		}
-		collapsed := CollapseWhitespace(text)
-		c.writeText(collapsed)
+	}
+	if len(line) > 0 {
+		lines = append(lines, line)
+	}
+
+	if c.pdf.GetX() > lMargin {
+		c.pdf.Ln(c.lineHeight())
+	}
+	oldR, oldG, oldB := c.pdf.GetTextColor()
+	for _, line := range lines {
+		last := &line[len(line)-1]
+		last.text = strings.TrimRight(last.text, " ")
+		c.setFont(last.family, last.style, last.size)
+		last.w = c.textWidth(last.text)
+		if c.rtl || lineHasRTL(line) {
+			line = c.bidiLine(line)
+		}
+
+		lineW, lineH := 0.0, 0.0
+		for _, seg := range line {
+			lineW += seg.w
+			if h := seg.size * 0.4; h > lineH {
+				lineH = h
+			}
+		}
+		x := lMargin + (usableW-lineW)/2
+		switch align {
+		case "R":
+			x = pageW - rMargin - lineW
+		case "L":
+			x = lMargin
+		}
+		c.pdf.SetX(x)
+		for _, seg := range line {
+			c.setFont(seg.family, seg.style, seg.size)
+			c.pdf.SetTextColor(seg.r, seg.g, seg.b)
+			c.pdf.CellFormat(seg.w, lineH, c.encodeText(seg.text), "", 0, "", false, 0, "")
+		}
+		c.pdf.Ln(lineH)
+	}
+	c.pdf.SetTextColor(oldR, oldG, oldB)
+	c.applyFont()
+}
+
+// pdfSegment is a piece of a laid out line, set in a single font.
+type pdfSegment struct {
+	pdfRun
+	w float64
+}
+
+// lineHasRTL reports whether any segment of a line holds right-to-left text.
+func lineHasRTL(line []pdfSegment) bool {
+	for _, seg := range line {
+		if HasRTL(seg.text) {
+			return true
+		}
+	}
+	return false
+}
+
+// bidiLine splits the segments of a line where the text direction changes and
+// returns them in display order, with right-to-left text reversed.
+func (c *HTMLToPDFConverter) bidiLine(line []pdfSegment) []pdfSegment {
+	var sb strings.Builder
+	for _, seg := range line {
+		sb.WriteString(seg.text)
+	}
+	levels := bidiLevels(sb.String(), c.rtl)
+
+	var parts []pdfSegment
+	var partLevels []int
+	offset := 0
+	for _, seg := range line {
+		runes := []rune(seg.text)
+		start := 0
+		for i := 1; i <= len(runes); i++ {
+			if i == len(runes) || levels[offset+i] != levels[offset+start] {
+				part := seg
+				part.text = string(runes[start:i])
+				parts = append(parts, part)
+				partLevels = append(partLevels, levels[offset+start])
+				start = i
+			}
+		}
+		offset += len(runes)
+	}
+
+	ordered := make([]pdfSegment, 0, len(parts))
+	for _, i := range visualOrder(partLevels) {
+		part := parts[i]
+		if partLevels[i]%2 == 1 {
+			part.text = bidi.ReverseString(part.text)
+		}
+		c.setFont(part.family, part.style, part.size)
+		part.w = c.textWidth(part.text)
+		ordered = append(ordered, part)
+	}
+	return ordered
+}
+
+// textWidth measures text in the current font. The page count alias is
+// measured as a two-digit number, since that is what it is replaced with.
+func (c *HTMLToPDFConverter) textWidth(text string) float64 {
+	return c.pdf.GetStringWidth(c.encodeText(strings.ReplaceAll(text, pdfPagesAlias, "00")))
+}
+
+// splitWords splits text into words, each keeping the spaces that follow it.
+func splitWords(text string) []string {
+	var words []string
+	start := 0
+	for i := 1; i < len(text); i++ {
+		if text[i] != ' ' && text[i-1] == ' ' {
+			words = append(words, text[start:i])
+			start = i
+		}
+	}
+	return append(words, text[start:])
+}
+
+// walkPDF renders an element of the document model into the PDF.
+func (c *HTMLToPDFConverter) walkPDF(e *Element) {
+	if !c.guard.enter() {
+		return
+	}
+	if e.Kind == KindText {
+		c.writeTextPDF(e.Text)
+		return
+	}
+	if e.Kind == KindField {
+		c.writeText(c.pageFieldText(e.Field))
 		return
 	}
 
-	if n.Type != html.ElementNode {
-		c.processChildrenPDF(n)
+	if e.Align != "" {
+		oldAlign := c.align
+		c.flushAligned()
+		c.align = pdfAlign(e.Align)
+		defer func() {
+			c.flushAligned()
+			c.align = oldAlign
+		}()
+	}
+
+	if e.Dir != "" {
+		oldDir, oldRTL, oldAlign := c.dir, c.rtl, c.align
+		c.flushAligned()
+		c.dir, c.rtl = e.Dir, e.Dir == "rtl"
+		if c.rtl && c.align == "" {
+			c.align = "R"
+		}
+		defer func() {
+			c.flushAligned()
+			c.dir, c.rtl, c.align = oldDir, oldRTL, oldAlign
+		}()
+	}
+
+	if family := c.fontFor(e.Style.FontFamily); family != "" {
+		oldFamily := c.fontFamily
+		c.fontFamily = family
+		c.applyFont()
+		defer func() {
+			c.fontFamily = oldFamily
+			c.applyFont()
+		}()
+	}
+
+	if h := c.handlers[e.Type]; h != nil && c.bypass != e {
+		if err := h(e.Source, &PDFContext{PDF: c.pdf, Element: e, c: c}); err != nil {
+			c.guard.fail(handlerError(e.Type, err))
+		}
 		return
 	}
+	c.bypass = nil
+	defer c.applyStylePDF(e.Style)()
 
-	nodeType := EffectiveNodeType(n)
-	switch nodeType {
-	case "head", "title", "style", "script", "meta", "link":
+	switch e.Kind {
+	case KindRegion:
+		// Rendered on the pages, see Document.Headers.
+	case KindUnsupported:
+		c.guard.warn(e.Source, e.Reason)
+	case KindHeading:
+		c.pdfHeading(e, pdfHeadingSizes[e.Level])
+	case KindParagraph:
+		if e.Implicit {
+			c.pdfFlow(e)
+		} else {
+			c.pdfBlock(e, 2, 3)
+		}
+	case KindPre:
+		oldPre := c.pre
+		c.pre = true
+		c.pdfBlock(e, 2, 3)
+		c.pre = oldPre
+	case KindTableCell:
+		c.pdfFlow(e)
+	case KindBreak:
+		c.lineBreakPDF()
+	case KindRule:
+		c.pdfHR()
+	case KindTable:
+		c.processTablePDF(e)
+	case KindList:
+		c.processListPDF(e)
+	case KindImage:
+		c.processImagePDF(e)
+	default:
+		c.processChildrenPDF(e)
+	}
+}
+
+// pdfHeadingSizes are the font sizes of headings by level, in points.
+var pdfHeadingSizes = [7]float64{1: 22, 2: 18, 3: 14, 4: 12, 5: 10, 6: 9}
+
+// writeTextPDF writes the text of a text element. Whitespace is collapsed as
+// in HTML, except in preformatted text, whose line breaks are kept.
+func (c *HTMLToPDFConverter) writeTextPDF(text string) {
+	if strings.TrimSpace(text) == "" {
 		return
-	case "h1":
-		c.pdfHeading(n, 22)
-	case "h2":
-		c.pdfHeading(n, 18)
-	case "h3":
-		c.pdfHeading(n, 14)
-	case "h4":
-		c.pdfHeading(n, 12)
-	case "h5":
-		c.pdfHeading(n, 10)
-	case "h6":
-		c.pdfHeading(n, 9)
-	case "p":
-		c.pdfBlock(n, 2, 3)
-	case "div", "section", "article", "nav", "main", "span":
-		c.processChildrenPDF(n)
-	case "center":
-		c.processCenterPDF(n)
-	case "br":
+	}
+	if !c.pre {
+		c.writeText(CollapseWhitespace(text))
+		return
+	}
+	for i, line := range strings.Split(text, "\n") {
+		if i > 0 {
+			c.lineBreakPDF()
+		}
+		if line != "" {
+			c.writeText(line)
+		}
+	}
+}
+
+// lineBreakPDF ends the current line.
+func (c *HTMLToPDFConverter) lineBreakPDF() {
+	if c.align != "" {
+		c.flushAligned()
+	} else {
 		c.pdf.Ln(c.lineHeight())
-	case "hr":
-		c.pdfHR()
-	case "b", "strong":
-		c.formattedPDF(n, "B")
-	case "i", "em":
-		c.formattedPDF(n, "I")
-	case "u":
-		c.formattedPDF(n, "U")
-	case "font":
-		c.processFontPDF(n)
-	case "table":
-		c.processTablePDF(n)
-	case "ul":
-		c.processListPDF(n, false)
-	case "ol":
-		c.processListPDF(n, true)
-	case "a":
-		oldStyle := c.fontStyle
+	}
+}
+
+// applyStylePDF applies the inline formatting of an element to the current
+// font and text color, returning a func that restores them.
+func (c *HTMLToPDFConverter) applyStylePDF(s Style) func() {
+	if s == (Style{}) {
+		return func() {}
+	}
+	oldStyle, oldSize, oldFamily := c.fontStyle, c.fontSize, c.fontFamily
+	oldR, oldG, oldB := c.pdf.GetTextColor()
+	if s.Bold {
+		c.fontStyle = c.addStyle(c.fontStyle, "B")
+	}
+	if s.Italic {
+		c.fontStyle = c.addStyle(c.fontStyle, "I")
+	}
+	if s.Underline {
 		c.fontStyle = c.addStyle(c.fontStyle, "U")
-		c.pdf.SetTextColor(0, 0, 255)
-		c.processChildrenPDF(n)
-		c.pdf.SetTextColor(0, 0, 0)
-		c.fontStyle = oldStyle
+	}
+	if s.FontSize > 0 {
+		c.fontSize = s.FontSize
+	}
+	if s.Code && !c.utf8 {
+		// Core fonts only: an embedded font may hold glyphs Courier lacks.
+		c.fontFamily = "Courier"
+	}
+	if s.Color != "" {
+		c.pdf.SetTextColor(ParseHexToRGB(s.Color))
+	}
+	c.applyFont()
+	return func() {
+		c.fontStyle, c.fontSize, c.fontFamily = oldStyle, oldSize, oldFamily
+		c.pdf.SetTextColor(oldR, oldG, oldB)
 		c.applyFont()
-	case "header":
-		c.processChildrenPDF(n)
-	case "footer":
-		c.processFooterPDF(n)
-	case "img":
-		// Skip images
-	default:
-		c.processChildrenPDF(n)
 	}
 }
 
-func (c *HTMLToPDFConverter) pdfBlock(n *html.Node, spaceBefore, spaceAfter float64) {
+func (c *HTMLToPDFConverter) pdfBlock(e *Element, spaceBefore, spaceAfter float64) {
+	c.flushAligned()
 	c.pdf.Ln(spaceBefore)
 	lMargin, _, _, _ := c.pdf.GetMargins()
 	c.pdf.SetX(lMargin)
-	c.processChildrenPDF(n)
+	restore := c.startDirection(e.TextContent())
+	c.processChildrenPDF(e)
+	c.flushAligned()
+	restore()
 	c.pdf.Ln(spaceAfter)
 }
 
-func (c *HTMLToPDFConverter) pdfHeading(n *html.Node, size float64) {
+// pdfFlow lays out inline content that is not in a paragraph of its own, such
+// as text between blocks or in a layout table cell, on lines of its own
+// without paragraph spacing.
+func (c *HTMLToPDFConverter) pdfFlow(e *Element) {
+	c.flushAligned()
+	restore := c.startDirection(e.TextContent())
+	c.processChildrenPDF(e)
+	c.flushAligned()
+	restore()
+	if lMargin, _, _, _ := c.pdf.GetMargins(); c.pdf.GetX() > lMargin {
+		c.pdf.Ln(c.lineHeight())
+	}
+}
+
+// startDirection sets the direction of a block from its dir attribute or,
+// without one, from its text. Blocks holding right-to-left text are laid out
+// line by line so they can be reordered for display, right aligned when the
+// block itself reads right to left. It returns a func restoring the state.
+func (c *HTMLToPDFConverter) startDirection(text string) func() {
+	oldRTL, oldAlign := c.rtl, c.align
+	c.rtl = c.dir == "rtl" || (c.dir == "" && IsRTLText(text))
+	if c.align == "" {
+		switch {
+		case c.rtl:
+			c.align = "R"
+		case HasRTL(text):
+			c.align = "L"
+		}
+	}
+	return func() {
+		c.rtl, c.align = oldRTL, oldAlign
+	}
+}
+
+func (c *HTMLToPDFConverter) pdfHeading(e *Element, size float64) {
+	c.flushAligned()
 	c.pdf.Ln(6)
 	oldSize := c.fontSize
 	oldStyle := c.fontStyle
@@ -164,17 +668,21 @@ func (c *HTMLToPDFConverter) pdfHeading(n *html.Node, size float64) {
 	c.fontSize = size
 	c.applyFont()
 
-	text := strings.TrimSpace(ExtractText(n))
-	lMargin, _, _, _ := c.pdf.GetMargins()
-	c.pdf.SetX(lMargin)
-	pageW, _ := c.pdf.GetPageSize()
-	_, _, rMargin, _ := c.pdf.GetMargins()
-	usableW := pageW - lMargin - rMargin
-	align := ""
-	if c.centered {
-		align = "C"
+	text := strings.TrimSpace(e.TextContent())
+	if c.rtl || HasRTL(text) {
+		// Right-to-left text needs the line layout to be reordered for display.
+		restore := c.startDirection(text)
+		c.writeText(CollapseWhitespace(text))
+		c.flushAligned()
+		restore()
+	} else {
+		lMargin, _, _, _ := c.pdf.GetMargins()
+		c.pdf.SetX(lMargin)
+		pageW, _ := c.pdf.GetPageSize()
+		_, _, rMargin, _ := c.pdf.GetMargins()
+		usableW := pageW - lMargin - rMargin
+		c.pdf.MultiCell(usableW, size*0.5, c.encodeText(text), "", c.align, false)
 	}
-	c.pdf.MultiCell(usableW, size*0.5, c.tr(text), "", align, false)
 	c.pdf.Ln(2)
 
 	c.fontSize = oldSize
@@ -183,6 +691,7 @@ func (c *HTMLToPDFConverter) pdfHeading(n *html.Node, size float64) {
 }
 
 func (c *HTMLToPDFConverter) pdfHR() {
+	c.flushAligned()
 	c.pdf.Ln(4)
 	pageW, _ := c.pdf.GetPageSize()
 	lMargin, _, rMargin, _ := c.pdf.GetMargins()
@@ -194,189 +703,383 @@ func (c *HTMLToPDFConverter) pdfHR() {
 	c.pdf.Ln(4)
 }
 
-func (c *HTMLToPDFConverter) formattedPDF(n *html.Node, style string) {
-	oldStyle := c.fontStyle
-	c.fontStyle = c.addStyle(c.fontStyle, style)
-	c.applyFont()
-	c.processChildrenPDF(n)
-	c.fontStyle = oldStyle
-	c.applyFont()
+// pageFieldText returns the text written for a page-number placeholder in the body.
+func (c *HTMLToPDFConverter) pageFieldText(field string) string {
+	if field == "pages" {
+		return pdfPagesAlias
+	}
+	return strconv.Itoa(c.pageNumber())
 }
 
-func (c *HTMLToPDFConverter) processFontPDF(n *html.Node) {
-	attrs := GetAttrMap(n.Attr)
-	oldSize := c.fontSize
-	oldFamily := c.fontFamily
-	oldR, oldG, oldB := c.pdf.GetTextColor()
+// pageNumber returns the page number displayed on the current page.
+func (c *HTMLToPDFConverter) pageNumber() int {
+	return c.pdf.PageNo() + c.section.offset
+}
 
-	if val, ok := attrs["size"]; ok {
-		switch val {
-		case "1":
-			c.fontSize = 8
-		case "2":
-			c.fontSize = 10
-		case "3":
-			c.fontSize = 12
-		case "4":
-			c.fontSize = 14
-		case "5":
-			c.fontSize = 18
-		case "6":
-			c.fontSize = 24
-		case "7":
-			c.fontSize = 36
-		}
+// startSectionPDF registers the page setup and the headers and footers of a
+// document before its body is laid out, then starts the section on a new
+// page. Elements for the same page variant are rendered one after another.
+func (c *HTMLToPDFConverter) startSectionPDF(doc *Document, page PageSetup) {
+	next := newPDFSection(page)
+	for variant, regions := range doc.Headers {
+		next.headers[variant] = &pdfRegion{elements: regions}
 	}
-
-	if val, ok := attrs["color"]; ok {
-		r, g, b := ParseHexToRGB(val)
-		c.pdf.SetTextColor(r, g, b)
+	for variant, regions := range doc.Footers {
+		next.footers[variant] = &pdfRegion{elements: regions}
 	}
 
-	if val, ok := attrs["face"]; ok {
-		switch strings.ToLower(val) {
-		case "arial", "helvetica":
-			c.fontFamily = "Arial"
-		case "times", "times new roman":
-			c.fontFamily = "Times"
-		case "courier", "courier new":
-			c.fontFamily = "Courier"
-		default:
-			c.fontFamily = "Arial"
+	next.titlePage = next.headers["first"] != nil || next.footers["first"] != nil
+	next.evenOdd = c.section.evenOdd || next.headers["even"] != nil || next.footers["even"] != nil
+	if len(next.headers) == 0 {
+		next.headers = c.section.headers
+	}
+	if len(next.footers) == 0 {
+		// Copy the inherited footers: measuring them for this page setup
+		// must not change the height the previous section's last footer
+		// is drawn with, which only happens once this page is added.
+		for variant, f := range c.section.footers {
+			r := *f
+			next.footers[variant] = &r
 		}
 	}
+	for _, f := range next.footers {
+		f.height = c.measureRegionPDF(f.elements, page, 9, "C")
+	}
 
-	c.applyFont()
-	c.processChildrenPDF(n)
+	c.page = page
+	c.next = next
+	orientation := "P"
+	if page.Orientation == Landscape {
+		orientation = "L"
+	}
+	c.pdf.AddPageFormat(orientation, gofpdf.SizeType{Wd: page.Size.Width, Ht: page.Size.Height})
+}
 
-	c.fontSize = oldSize
-	c.fontFamily = oldFamily
-	c.pdf.SetTextColor(oldR, oldG, oldB)
-	c.applyFont()
+// pageVariant returns which header and footer variant applies to the current
+// page, following Word's rules: with a first-page variant defined the first
+// page never falls back to the default one, and likewise for even pages.
+func (c *HTMLToPDFConverter) pageVariant() string {
+	switch {
+	case c.section.titlePage && c.pdf.PageNo() == c.section.firstPage:
+		return "first"
+	case c.section.evenOdd && c.pageNumber()%2 == 0:
+		return "even"
+	}
+	return "default"
 }
 
-func (c *HTMLToPDFConverter) processFooterPDF(n *html.Node) {
-	var lines []string
-	var extractLines func(*html.Node)
-	extractLines = func(node *html.Node) {
-		for ch := node.FirstChild; ch != nil; ch = ch.NextSibling {
-			if ch.Type == html.TextNode {
-				text := strings.TrimSpace(ch.Data)
-				if text != "" {
-					lines = append(lines, text)
-				}
-			} else if ch.Type == html.ElementNode {
-				if ch.Data == "br" {
-					continue
-				}
-				extractLines(ch)
-			}
+// startPagePDF runs at the top of every page. It switches to a pending
+// section, draws the page's header below which the body starts, and
+// reserves room for the page's footer.
+func (c *HTMLToPDFConverter) startPagePDF() {
+	if max := c.guard.limits.MaxPages; max > 0 && c.pdf.PageNo() > max {
+		// Stop gofpdf too, so text already being written adds no more pages.
+		err := &LimitError{Limit: "pages", Max: int64(max)}
+		c.guard.fail(err)
+		c.pdf.SetError(err)
+		return
+	}
+	if c.next != nil {
+		offset := c.section.offset
+		c.section, c.next = c.next, nil
+		c.section.firstPage = c.pdf.PageNo()
+		c.section.offset = offset
+		if start := c.section.page.PageNumberStart; start > 0 {
+			c.section.offset = start - c.section.firstPage
+		}
+		m := c.section.page.Margins
+		c.pdf.SetMargins(m.Left, m.Top, m.Right)
+	}
+
+	s := c.section
+	variant := c.pageVariant()
+	top := s.page.Margins.Top
+	if h := s.headers[variant]; h != nil {
+		if bottom := c.renderRegionPDF(h.elements, s.page.HeaderDistance, c.baseSize, "") + pdfHeaderGap; bottom > top {
+			top = bottom
 		}
 	}
-	extractLines(n)
+	lMargin, _, _, _ := c.pdf.GetMargins()
+	c.pdf.SetTopMargin(top)
+	c.pdf.SetXY(lMargin, top)
+
+	margin := s.page.Margins.Bottom
+	if f := s.footers[variant]; f != nil && s.page.FooterDistance+f.height+pdfHeaderGap > margin {
+		margin = s.page.FooterDistance + f.height + pdfHeaderGap
+	}
+	c.pdf.SetAutoPageBreak(true, margin)
+}
 
-	if len(lines) == 0 {
+// renderFooterPDF draws the page's footer above the bottom edge of the page.
+func (c *HTMLToPDFConverter) renderFooterPDF() {
+	f := c.section.footers[c.pageVariant()]
+	if f == nil {
 		return
 	}
+	_, pageH := c.pdf.GetPageSize()
+	c.renderRegionPDF(f.elements, pageH-c.section.page.FooterDistance-f.height, 9, "C")
+}
 
-	tr := c.tr
-	c.pdf.SetFooterFunc(func() {
-		c.pdf.SetY(-20)
-		c.pdf.SetFont("Arial", "", 9)
-		pageW, _ := c.pdf.GetPageSize()
-		lMargin, _, rMargin, _ := c.pdf.GetMargins()
-		usableW := pageW - lMargin - rMargin
-		for _, line := range lines {
-			c.pdf.SetX(lMargin)
-			c.pdf.CellFormat(usableW, 4, tr(line), "", 1, "C", false, 0, "")
+// renderRegionPDF lays out the children of header or footer elements starting
+// at y with the region's default font size and alignment, and the style of
+// each element, leaving the converter's own state untouched. It returns the Y
+// position below the content.
+func (c *HTMLToPDFConverter) renderRegionPDF(elements []*Element, y, size float64, align string) float64 {
+	oldStyle, oldSize, oldFamily, oldAlign, oldPending := c.fontStyle, c.fontSize, c.fontFamily, c.align, c.pending
+	auto, bMargin := c.pdf.GetAutoPageBreak()
+	c.pdf.SetAutoPageBreak(false, bMargin)
+
+	// The page may break inside an indented list; the region uses the page margin.
+	oldLMargin, _, _, _ := c.pdf.GetMargins()
+	lMargin := c.section.page.Margins.Left
+	c.pdf.SetLeftMargin(lMargin)
+	c.pdf.SetXY(lMargin, y)
+	c.fontStyle, c.fontSize, c.fontFamily, c.pending = "", size, c.baseFamily, nil
+	c.applyFont()
+	for _, e := range elements {
+		c.align = align
+		if e.Align != "" {
+			c.align = pdfAlign(e.Align)
 		}
-	})
-}
+		if family := c.fontFor(e.Style.FontFamily); family != "" {
+			c.fontFamily = family
+		}
+		restore := c.applyStylePDF(e.Style)
+		c.applyFont()
+		c.processChildrenPDF(e)
+		c.flushAligned()
+		restore()
+		c.fontFamily = c.baseFamily
+		c.applyFont()
+	}
 
-func (c *HTMLToPDFConverter) processCenterPDF(n *html.Node) {
-	oldCentered := c.centered
-	c.centered = true
-	c.processChildrenPDF(n)
-	c.centered = oldCentered
+	bottom := c.pdf.GetY()
+	if c.pdf.GetX() > lMargin {
+		bottom += c.lineHeight()
+	}
+
+	c.fontStyle, c.fontSize, c.fontFamily, c.align, c.pending = oldStyle, oldSize, oldFamily, oldAlign, oldPending
+	c.applyFont()
+	c.pdf.SetLeftMargin(oldLMargin)
+	c.pdf.SetAutoPageBreak(auto, bMargin)
+	return bottom
 }
 
-func (c *HTMLToPDFConverter) processTablePDF(n *html.Node) {
-	attrs := GetAttrMap(n.Attr)
-	borderVal := attrs["border"]
-	isDataTable := borderVal != "" && borderVal != "0"
+// measureRegionPDF returns the height elements take up when rendered as a
+// header or footer, by laying them out on a scratch document with the same
+// page geometry.
+func (c *HTMLToPDFConverter) measureRegionPDF(elements []*Element, page PageSetup, size float64, align string) float64 {
+	pageW, pageH := page.Size.Width, page.Size.Height
+	if page.Orientation == Landscape {
+		pageW, pageH = pageH, pageW
+	}
+	scratch := gofpdf.NewCustom(&gofpdf.InitType{UnitStr: "mm", Size: gofpdf.SizeType{Wd: pageW, Ht: pageH}})
+	scratch.SetMargins(page.Margins.Left, page.Margins.Top, page.Margins.Right)
+	for family, font := range c.fonts {
+		for style, data := range font.styles {
+			scratch.AddUTF8FontFromBytes(family, style, data)
+		}
+	}
+	scratch.AddPage()
+	m := &HTMLToPDFConverter{
+		pdf:        scratch,
+		fontSize:   c.baseSize,
+		fontFamily: c.baseFamily,
+		tr:         c.tr,
+		fonts:      c.fonts,
+		fallbacks:  c.fallbacks,
+		handlers:   c.handlers,
+		noFiles:    c.noFiles,
+		resources:  c.resources,
+		baseFamily: c.baseFamily,
+		baseSize:   c.baseSize,
+		section:    newPDFSection(page),
+	}
+	return m.renderRegionPDF(elements, 0, size, align)
+}
 
-	if !isDataTable {
-		c.processTableChildrenAsFlow(n)
+// processImagePDF places an image from a local file or a data: URI on its own
+// line, honoring width/height attributes (in CSS pixels) and the current
+// alignment. Images that cannot be loaded are skipped with a warning.
+func (c *HTMLToPDFConverter) processImagePDF(e *Element) {
+	name, info := c.registerImagePDF(e.Src)
+	if info == nil {
+		c.guard.warn(e.Source, "image could not be loaded")
 		return
 	}
 
-	c.pdf.Ln(4)
+	const pxToMM = 25.4 / 96
+	w, h := e.ImageWidth*pxToMM, e.ImageHeight*pxToMM
+	switch {
+	case w == 0 && h == 0:
+		w, h = info.Extent()
+	case w == 0:
+		w = h * info.Width() / info.Height()
+	case h == 0:
+		h = w * info.Height() / info.Width()
+	}
+
 	pageW, _ := c.pdf.GetPageSize()
 	lMargin, _, rMargin, _ := c.pdf.GetMargins()
 	usableW := pageW - lMargin - rMargin
+	if w > usableW {
+		h = h * usableW / w
+		w = usableW
+	}
 
-	tableW := usableW
-	if val, ok := attrs["width"]; ok {
-		if strings.HasSuffix(val, "%") {
-			pct := strings.TrimSuffix(val, "%")
-			if p, err := strconv.ParseFloat(pct, 64); err == nil {
-				tableW = usableW * p / 100.0
-			}
+	x := lMargin
+	switch c.align {
+	case "C":
+		x = lMargin + (usableW-w)/2
+	case "R":
+		x = pageW - rMargin - w
+	}
+	c.flushAligned()
+	if c.pdf.GetX() > lMargin {
+		c.pdf.Ln(c.lineHeight())
+	}
+	c.pdf.ImageOptions(name, x, -1, w, h, true, gofpdf.ImageOptions{}, 0, "")
+	c.pdf.SetX(lMargin)
+}
+
+// registerImagePDF loads the image referenced by src and returns the name it
+// was registered under, or a nil info if it is missing, unsupported or a file
+// while local images are disabled.
+func (c *HTMLToPDFConverter) registerImagePDF(src string) (string, *gofpdf.ImageInfoType) {
+	if src == "" || c.pdf.Err() {
+		return "", nil
+	}
+	if info := c.pdf.GetImageInfo(src); info != nil {
+		return src, info
+	}
+
+	var data []byte
+	var imgType string
+	if strings.HasPrefix(src, "data:") {
+		meta, payload, ok := strings.Cut(strings.TrimPrefix(src, "data:"), ",")
+		if !ok || !strings.HasSuffix(meta, ";base64") {
+			return "", nil
+		}
+		if !c.imageWithinLimit(int64(base64.StdEncoding.DecodedLen(len(payload)))) {
+			return "", nil
+		}
+		decoded, err := base64.StdEncoding.DecodeString(payload)
+		if err != nil {
+			return "", nil
+		}
+		data = decoded
+		imgType = strings.TrimPrefix(strings.TrimSuffix(meta, ";base64"), "image/")
+	} else {
+		path := c.imagePath(src)
+		if path == "" {
+			return "", nil
+		}
+		if fi, err := os.Stat(path); err != nil || !c.imageWithinLimit(fi.Size()) {
+			return "", nil
 		}
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return "", nil
+		}
+		data = content
+		imgType = strings.TrimPrefix(filepath.Ext(src), ".")
 	}
 
-	type cellData struct {
-		text     string
-		isHeader bool
-	}
-	var rows [][]cellData
-
-	var collectRows func(*html.Node)
-	collectRows = func(curr *html.Node) {
-		for ch := curr.FirstChild; ch != nil; ch = ch.NextSibling {
-			if ch.Type == html.ElementNode {
-				if EffectiveNodeType(ch) == "tr" {
-					var row []cellData
-					var collectCells func(*html.Node)
-					collectCells = func(cellContainer *html.Node) {
-						for cell := cellContainer.FirstChild; cell != nil; cell = cell.NextSibling {
-							if cell.Type == html.ElementNode {
-								cellType := EffectiveNodeType(cell)
-								if cellType == "td" || cellType == "th" {
-									text := strings.TrimSpace(ExtractText(cell))
-									row = append(row, cellData{text: text, isHeader: cellType == "th"})
-								} else if cellType == "div" || cellType == "span" {
-									collectCells(cell)
-								}
-							}
-						}
-					}
-					collectCells(ch)
-
-					if len(row) > 0 {
-						rows = append(rows, row)
-					}
-				} else if ch.FirstChild != nil {
-					collectRows(ch)
-				}
+	switch strings.ToLower(imgType) {
+	case "png", "jpg", "jpeg", "gif":
+	default:
+		return "", nil
+	}
+
+	name := src
+	if strings.HasPrefix(src, "data:") {
+		h := fnv.New64a()
+		h.Write(data)
+		name = fmt.Sprintf("data-%x", h.Sum64())
+	}
+	info := c.pdf.RegisterImageOptionsReader(name, gofpdf.ImageOptions{ImageType: imgType}, bytes.NewReader(data))
+	if c.pdf.Err() {
+		c.pdf.ClearError()
+		return "", nil
+	}
+	return name, info
+}
+
+// imagePath returns the file an image source refers to: the first match of a
+// relative source in the resource directories, or else the source itself.
+// With Options.NoLocalImages only sources inside a resource directory are
+// found, and "" is returned for the others.
+func (c *HTMLToPDFConverter) imagePath(src string) string {
+	local := filepath.IsLocal(filepath.FromSlash(src))
+	if c.noFiles && !local {
+		return ""
+	}
+	if !filepath.IsAbs(src) {
+		for _, dir := range c.resources {
+			path := filepath.Join(dir, filepath.FromSlash(src))
+			if fi, err := os.Stat(path); err == nil && !fi.IsDir() {
+				return path
 			}
 		}
 	}
-	collectRows(n)
+	if c.noFiles {
+		return ""
+	}
+	return src
+}
+
+// imageWithinLimit reports whether an image of size bytes is within
+// Limits.MaxImageBytes, stopping the conversion if it is not.
+func (c *HTMLToPDFConverter) imageWithinLimit(size int64) bool {
+	if max := c.guard.limits.MaxImageBytes; max > 0 && size > max {
+		c.guard.fail(&LimitError{Limit: "image bytes", Max: max})
+		return false
+	}
+	return true
+}
+
+// pdfAlign maps an HTML align attribute value to a gofpdf alignment string.
+func pdfAlign(val string) string {
+	switch strings.ToLower(val) {
+	case "center":
+		return "C"
+	case "right":
+		return "R"
+	}
+	return ""
+}
 
+func (c *HTMLToPDFConverter) processTablePDF(e *Element) {
+	if !e.Border {
+		// Layout tables only position content: each cell flows in turn.
+		c.processChildrenPDF(e)
+		return
+	}
+
+	rows := e.Children
 	if len(rows) == 0 {
 		return
 	}
 
+	c.flushAligned()
+	c.pdf.Ln(4)
+	pageW, _ := c.pdf.GetPageSize()
+	lMargin, _, rMargin, _ := c.pdf.GetMargins()
+	usableW := pageW - lMargin - rMargin
+
+	tableW := usableW
+	if strings.HasSuffix(e.Width, "%") {
+		if p, err := strconv.ParseFloat(strings.TrimSuffix(e.Width, "%"), 64); err == nil {
+			tableW = usableW * p / 100.0
+		}
+	}
+
 	maxCols := 0
 	for _, row := range rows {
-		if len(row) > maxCols {
-			maxCols = len(row)
+		if len(row.Children) > maxCols {
+			maxCols = len(row.Children)
 		}
 	}
-	if maxCols == 0 {
-		return
-	}
 	colW := tableW / float64(maxCols)
 	rowH := 7.0
 
@@ -384,13 +1087,14 @@ func (c *HTMLToPDFConverter) processTablePDF(n *html.Node) {
 
 	for _, row := range rows {
 		c.pdf.SetX(tableX)
-		for _, cell := range row {
-			if cell.isHeader {
-				c.pdf.SetFont(c.fontFamily, "B", c.fontSize)
+		for _, cell := range row.Children {
+			if cell.Header {
+				c.setFont(c.fontFamily, "B", c.fontSize)
 			} else {
-				c.pdf.SetFont(c.fontFamily, c.fontStyle, c.fontSize)
+				c.setFont(c.fontFamily, c.fontStyle, c.fontSize)
 			}
-			c.pdf.CellFormat(colW, rowH, c.tr(cell.text), "1", 0, "", false, 0, "")
+			text := strings.TrimSpace(cell.TextContent())
+			c.pdf.CellFormat(colW, rowH, c.encodeText(visualText(text, c.rtl || IsRTLText(text))), "1", 0, "", false, 0, "")
 		}
 		c.pdf.Ln(rowH)
 	}
@@ -398,57 +1102,128 @@ func (c *HTMLToPDFConverter) processTablePDF(n *html.Node) {
 	c.pdf.Ln(4)
 }
 
-func (c *HTMLToPDFConverter) processTableChildrenAsFlow(n *html.Node) {
-	for ch := n.FirstChild; ch != nil; ch = ch.NextSibling {
-		if ch.Type == html.ElementNode {
-			switch EffectiveNodeType(ch) {
-			case "tr":
-				c.processTableChildrenAsFlow(ch)
-			case "td", "th":
-				c.processChildrenPDF(ch)
-			case "thead", "tbody", "tfoot", "div", "span":
-				c.processTableChildrenAsFlow(ch)
-			default:
-				c.walkPDF(ch)
-			}
-		}
+// processListPDF lays out a list with each item on its own line after its
+// bullet or number. Nested lists are indented further.
+func (c *HTMLToPDFConverter) processListPDF(e *Element) {
+	c.flushAligned()
+	lMargin, _, _, _ := c.pdf.GetMargins()
+	if c.pdf.GetX() > lMargin {
+		c.pdf.Ln(c.lineHeight())
 	}
-}
-
-func (c *HTMLToPDFConverter) processListPDF(n *html.Node, ordered bool) {
 	c.pdf.Ln(2)
-	index := 1
-	lMargin, _, _, _ := c.pdf.GetMargins()
 	indent := 10.0
+	c.pdf.SetLeftMargin(lMargin + indent)
 
-	for li := n.FirstChild; li != nil; li = li.NextSibling {
-		if li.Type == html.ElementNode && EffectiveNodeType(li) == "li" {
-			c.pdf.SetX(lMargin + indent)
-			c.applyFont()
-			if ordered {
-				prefix := fmt.Sprintf("%d. ", index)
-				c.pdf.Write(c.lineHeight(), prefix)
-				index++
-			} else {
-				c.pdf.Write(c.lineHeight(), "- ")
-			}
-			text := strings.TrimSpace(ExtractText(li))
-			c.pdf.Write(c.lineHeight(), c.tr(text))
+	for i, li := range e.Children {
+		c.pdf.SetX(lMargin + indent)
+		c.applyFont()
+		if e.Ordered {
+			c.pdf.Write(c.lineHeight(), fmt.Sprintf("%d. ", i+1))
+		} else {
+			c.pdf.Write(c.lineHeight(), "- ")
+		}
+		c.walkPDF(li)
+		c.flushAligned()
+		if c.pdf.GetX() > lMargin+indent {
 			c.pdf.Ln(c.lineHeight() + 1)
 		}
 	}
+	c.pdf.SetLeftMargin(lMargin)
+	c.pdf.SetX(lMargin)
 	c.pdf.Ln(2)
 }
 
-func (c *HTMLToPDFConverter) processChildrenPDF(n *html.Node) {
-	for ch := n.FirstChild; ch != nil; ch = ch.NextSibling {
+func (c *HTMLToPDFConverter) processChildrenPDF(e *Element) {
+	for _, ch := range e.Children {
 		c.walkPDF(ch)
 	}
 }
 
+// Warnings returns the warnings about unsupported content collected by all
+// conversions so far.
+func (c *HTMLToPDFConverter) Warnings() []Warning {
+	return append([]Warning(nil), c.guard.warnings...)
+}
+
 // SaveToFile saves the PDF to a file.
 func (c *HTMLToPDFConverter) SaveToFile(filename string) error {
-	return c.pdf.OutputFileAndClose(filename)
+	out, err := c.Bytes()
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filename, out, 0644)
+}
+
+// WriteTo writes the PDF document to w.
+func (c *HTMLToPDFConverter) WriteTo(w io.Writer) (int64, error) {
+	out, err := c.Bytes()
+	if err != nil {
+		return 0, err
+	}
+	n, err := w.Write(out)
+	return int64(n), err
+}
+
+// Bytes returns the PDF document. gofpdf closes the document on output, so it
+// is rendered once and kept for later calls, and no more content can be
+// converted into it.
+func (c *HTMLToPDFConverter) Bytes() ([]byte, error) {
+	if c.out != nil {
+		return c.out, nil
+	}
+	var buf bytes.Buffer
+	if err := c.pdf.Output(&buf); err != nil {
+		return nil, err
+	}
+	out := buf.Bytes()
+	if c.lang != "" {
+		var err error
+		if out, err = setCatalogLang(out, c.lang); err != nil {
+			return nil, err
+		}
+	}
+	c.out = out
+	return out, nil
+}
+
+// setCatalogLang adds a /Lang entry to the document catalog of a PDF written
+// by gofpdf, which has no API for it. gofpdf writes the catalog as the last
+// object before the cross-reference table, so only the startxref offset
+// moves.
+func setCatalogLang(pdf []byte, lang string) ([]byte, error) {
+	lang = strings.Map(func(r rune) rune {
+		if r == '-' || (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') {
+			return r
+		}
+		return -1
+	}, lang)
+	if lang == "" {
+		return pdf, nil
+	}
+	catalog := []byte("/Type /Catalog\n")
+	at := bytes.LastIndex(pdf, catalog)
+	startxref := bytes.LastIndex(pdf, []byte("startxref\n"))
+	if at < 0 || startxref < at {
+		return nil, fmt.Errorf("failed to set PDF language: unexpected document structure")
+	}
+	end := startxref + len("startxref\n")
+	numEnd := bytes.IndexByte(pdf[end:], '\n')
+	if numEnd < 0 {
+		return nil, fmt.Errorf("failed to set PDF language: unexpected document structure")
+	}
+	offset, err := strconv.Atoi(string(pdf[end : end+numEnd]))
+	if err != nil {
+		return nil, fmt.Errorf("failed to set PDF language: %w", err)
+	}
+
+	entry := "/Lang (" + lang + ")\n"
+	var out bytes.Buffer
+	out.Write(pdf[:at+len(catalog)])
+	out.WriteString(entry)
+	out.Write(pdf[at+len(catalog) : end])
+	out.WriteString(strconv.Itoa(offset + len(entry)))
+	out.Write(pdf[end+numEnd:])
+	return out.Bytes(), nil
 }
 
 func (c *HTMLToPDFConverter) addStyle(current, add string) string {
@@ -1,9 +1,20 @@
 package converter
 
 import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"go/build"
+	"image"
+	"image/color"
+	"image/png"
 	"os"
 	"path/filepath"
+	"regexp"
+	"strings"
 	"testing"
+	"unicode/utf16"
 )
 
 func TestPDFConverterBasic(t *testing.T) {
@@ -290,3 +301,657 @@ func TestPDFConverterComplexDocument(t *testing.T) {
 		t.Errorf("complex PDF seems too small: %d bytes", info.Size())
 	}
 }
+
+func TestPDFConverterPageFields(t *testing.T) {
+	conv := NewHTMLToPDFConverter()
+	htmlContents := []string{
+		`<html><body>
+			<p>Content</p>
+			<footer><p>Page <span data-field="page"></span> of <span data-field="pages"></span></p></footer>
+		</body></html>`,
+		`<html><body><p>Second page, <span data-field="page"></span>/<span data-field="pages"></span></p></body></html>`,
+	}
+
+	content := pdfContent(t, conv, htmlContents...)
+	text := pdfText(content)
+	for _, want := range []string{"Page 1 of 2", "Second page, 2/2", "Page 2 of 2"} {
+		if !strings.Contains(text, want) {
+			t.Errorf("expected %q in the text, got %q", want, text)
+		}
+	}
+	if strings.Contains(content, pdfPagesAlias) {
+		t.Errorf("expected the %s alias to be replaced with the page count", pdfPagesAlias)
+	}
+}
+
+func TestPDFConverterPageNumberStartMeta(t *testing.T) {
+	conv := NewHTMLToPDFConverter()
+	text := pdfText(pdfContent(t, conv,
+		`<html><body><p>Cover</p></body></html>`,
+		`<html><head><meta name="page-number-start" content="7"></head><body>
+			<p>Chapter</p>
+			<footer><p>Page <span data-field="page"></span></p></footer>
+		</body></html>`,
+	))
+
+	if !strings.Contains(text, "Page 7") {
+		t.Errorf("expected the second section to be numbered from 7, got %q", text)
+	}
+}
+
+func TestPDFConverterRepeatingHeader(t *testing.T) {
+	dir := t.TempDir()
+	logo := filepath.Join(dir, "logo.png")
+	img := image.NewRGBA(image.Rect(0, 0, 40, 20))
+	for x := 0; x < 40; x++ {
+		img.Set(x, 10, color.RGBA{R: 200, A: 255})
+	}
+	f, err := os.Create(logo)
+	if err != nil {
+		t.Fatalf("could not create image: %v", err)
+	}
+	if err := png.Encode(f, img); err != nil {
+		t.Fatalf("could not encode image: %v", err)
+	}
+	f.Close()
+
+	conv := NewHTMLToPDFConverter()
+	htmlContents := []string{`<html><body>
+		<header align="right">
+			<img src="` + logo + `" width="80">
+			<p><b>Quarterly Report</b> <font face="Times">Confidential</font></p>
+			<hr>
+		</header>
+		` + strings.Repeat("<p>Body paragraph that fills the page.</p>", 120) + `
+	</body></html>`}
+
+	if err := conv.Convert(htmlContents); err != nil {
+		t.Fatalf("Convert failed: %v", err)
+	}
+	if conv.pdf.PageNo() < 2 {
+		t.Fatalf("expected content to span several pages, got %d", conv.pdf.PageNo())
+	}
+	if conv.section.headers["default"] == nil {
+		t.Error("expected header to be registered for every page")
+	}
+
+	tmpFile := filepath.Join(dir, "header.pdf")
+	if err := conv.SaveToFile(tmpFile); err != nil {
+		t.Fatalf("SaveToFile failed: %v", err)
+	}
+}
+
+func TestPDFConverterMissingImage(t *testing.T) {
+	conv := NewHTMLToPDFConverter()
+	htmlContents := []string{`<html><body>
+		<img src="does-not-exist.png"><img src="data:image/png;base64,!!!">
+		<p>Still rendered</p>
+	</body></html>`}
+
+	if err := conv.Convert(htmlContents); err != nil {
+		t.Fatalf("Convert failed: %v", err)
+	}
+
+	tmpFile := filepath.Join(t.TempDir(), "missing_image.pdf")
+	if err := conv.SaveToFile(tmpFile); err != nil {
+		t.Fatalf("SaveToFile failed: %v", err)
+	}
+}
+
+// pdfContent converts htmlContents with uncompressed content streams and
+// returns the whole file, so tests can look for the text and operators drawn.
+func pdfContent(t *testing.T, conv *HTMLToPDFConverter, htmlContents ...string) string {
+	t.Helper()
+	conv.pdf.SetCompression(false)
+	if err := conv.Convert(htmlContents); err != nil {
+		t.Fatalf("Convert failed: %v", err)
+	}
+	data, err := conv.Bytes()
+	if err != nil {
+		t.Fatalf("Bytes failed: %v", err)
+	}
+	return string(data)
+}
+
+// pdfTextOp matches a string drawn by a content stream.
+var pdfTextOp = regexp.MustCompile(`\((.*?)\)Tj`)
+
+// pdfText returns the strings drawn by the content streams of an
+// uncompressed PDF, joined in the order they are drawn.
+func pdfText(content string) string {
+	var b strings.Builder
+	for _, m := range pdfTextOp.FindAllStringSubmatch(content, -1) {
+		b.WriteString(m[1])
+	}
+	return b.String()
+}
+
+// pdfUnicodeText returns the text drawn in a content stream with an embedded
+// TrueType font, whose strings gofpdf writes as UTF-16BE.
+func pdfUnicodeText(content string) string {
+	unescape := strings.NewReplacer(`\\`, `\`, `\(`, "(", `\)`, ")", `\r`, "\r")
+	var b strings.Builder
+	for _, m := range pdfTextOp.FindAllStringSubmatch(content, -1) {
+		raw := []byte(unescape.Replace(m[1]))
+		units := make([]uint16, len(raw)/2)
+		for i := range units {
+			units[i] = uint16(raw[2*i])<<8 | uint16(raw[2*i+1])
+		}
+		b.WriteString(string(utf16.Decode(units)))
+	}
+	return b.String()
+}
+
+func TestPDFConverterRegionStyle(t *testing.T) {
+	conv := NewHTMLToPDFConverter()
+	content := pdfContent(t, conv, `<html><body>
+		<p>Content</p>
+		<footer style="font-family: Courier"><p>Confidential</p></footer>
+	</body></html>`)
+
+	if !strings.Contains(content, "/BaseFont /Courier") {
+		t.Error("expected the footer's own font family to be used")
+	}
+	if !strings.Contains(content, "(Confidential)Tj") {
+		t.Error("expected the footer text to be drawn")
+	}
+}
+
+// writeTestPNG writes a small PNG image to path.
+func writeTestPNG(t *testing.T, path string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, image.NewGray(image.Rect(0, 0, 4, 4))); err != nil {
+		t.Fatalf("could not encode image: %v", err)
+	}
+	if err := os.WriteFile(path, buf.Bytes(), 0644); err != nil {
+		t.Fatalf("could not write image: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func TestPDFConverterNoLocalImages(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "secret.png")
+	data := writeTestPNG(t, path)
+	uri := "data:image/png;base64," + base64.StdEncoding.EncodeToString(data)
+
+	conv := NewHTMLToPDFConverter(Options{NoLocalImages: true})
+	content := pdfContent(t, conv, `<html><body>
+		<img src="`+path+`"><img src="`+uri+`">
+		<footer><img src="`+path+`"></footer>
+	</body></html>`)
+
+	if n := strings.Count(content, "/Subtype /Image"); n != 1 {
+		t.Errorf("expected only the data: URI image to be embedded, got %d images", n)
+	}
+	if w := conv.Warnings(); len(w) != 2 || w[0].Reason != "image could not be loaded" {
+		t.Errorf("expected warnings for the file images, got %v", w)
+	}
+}
+
+func TestPDFConverterNoLocalImagesResourceDirs(t *testing.T) {
+	dir := t.TempDir()
+	os.Mkdir(filepath.Join(dir, "assets"), 0755)
+	writeTestPNG(t, filepath.Join(dir, "assets", "logo.png"))
+	writeTestPNG(t, filepath.Join(dir, "secret.png"))
+
+	conv := NewHTMLToPDFConverter(Options{NoLocalImages: true, ResourceDirs: []string{filepath.Join(dir, "assets")}})
+	content := pdfContent(t, conv, `<html><body>
+		<img src="logo.png">
+		<img src="../secret.png">
+		<img src="`+filepath.Join(dir, "secret.png")+`">
+	</body></html>`)
+
+	if n := strings.Count(content, "/Subtype /Image"); n != 1 {
+		t.Errorf("expected only the image inside the resource directory to be embedded, got %d images", n)
+	}
+	if w := conv.Warnings(); len(w) != 2 {
+		t.Errorf("expected warnings for the images outside the resource directory, got %v", w)
+	}
+}
+
+func TestPDFConverterResourceDirs(t *testing.T) {
+	dir := t.TempDir()
+	for _, sub := range []string{"first", "second/img"} {
+		os.MkdirAll(filepath.Join(dir, sub), 0755)
+	}
+	writeTestPNG(t, filepath.Join(dir, "second", "img", "logo.png"))
+
+	conv := NewHTMLToPDFConverter(Options{ResourceDirs: []string{filepath.Join(dir, "first"), filepath.Join(dir, "second")}})
+	content := pdfContent(t, conv, `<html><body>
+		<img src="img/logo.png">
+		\u003cimg src=\"img/logo.png\" width=\"20\"\u003e
+		<img src="img/missing.png">
+	</body></html>`)
+
+	if !strings.Contains(content, "/Subtype /Image") {
+		t.Error("expected the image found in the second resource directory to be embedded")
+	}
+	if w := conv.Warnings(); len(w) != 1 || w[0].Reason != "image could not be loaded" {
+		t.Errorf("expected a warning for the missing image only, got %v", w)
+	}
+}
+
+func TestPDFConverterRichFooter(t *testing.T) {
+	conv := NewHTMLToPDFConverter()
+	htmlContents := []string{`<html><body>
+		` + strings.Repeat("<p>Body paragraph that fills the page.</p>", 120) + `
+		<footer>
+			<p>Page <span data-field="page"></span> of <span data-field="pages"></span> &middot; <a href="https://example.com">example.com</a></p>
+			<p align="right"><b>Confidential</b></p>
+			<p>Line three</p><p>Line four</p><p>Line five</p>
+		</footer>
+	</body></html>`}
+
+	if err := conv.Convert(htmlContents); err != nil {
+		t.Fatalf("Convert failed: %v", err)
+	}
+	footer := conv.section.footers["default"]
+	if footer == nil || footer.height <= 0 {
+		t.Fatalf("expected footer height to be measured, got %+v", footer)
+	}
+	if _, margin := conv.pdf.GetAutoPageBreak(); margin < footer.height+conv.section.page.FooterDistance {
+		t.Errorf("page break margin %v does not leave room for footer of height %v", margin, footer.height)
+	}
+
+	tmpFile := filepath.Join(t.TempDir(), "rich_footer.pdf")
+	if err := conv.SaveToFile(tmpFile); err != nil {
+		t.Fatalf("SaveToFile failed: %v", err)
+	}
+}
+
+func TestSplitWords(t *testing.T) {
+	got := splitWords("Page 1  of 2 ")
+	want := []string{"Page ", "1  ", "of ", "2 "}
+	if strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Errorf("splitWords = %q, want %q", got, want)
+	}
+}
+
+func TestPDFConverterHeaderFooterVariants(t *testing.T) {
+	conv := NewHTMLToPDFConverter()
+	htmlContents := []string{`<html><body>
+		<header data-page="first"><h1>Annual Report</h1></header>
+		<header><p>Odd page header</p></header>
+		<header data-page="even"><p align="right">Even page header</p></header>
+		<footer data-page="even"><p>Page <span data-field="page"></span></p></footer>
+		` + strings.Repeat("<p>Body paragraph that fills the page.</p>", 200) + `
+	</body></html>`}
+
+	if err := conv.Convert(htmlContents); err != nil {
+		t.Fatalf("Convert failed: %v", err)
+	}
+	if !conv.section.titlePage || !conv.section.evenOdd {
+		t.Errorf("expected first and even variants to be enabled, got titlePage=%v evenOdd=%v", conv.section.titlePage, conv.section.evenOdd)
+	}
+	if conv.pdf.PageNo() < 3 {
+		t.Fatalf("expected at least 3 pages, got %d", conv.pdf.PageNo())
+	}
+
+	tmpFile := filepath.Join(t.TempDir(), "variants.pdf")
+	if err := conv.SaveToFile(tmpFile); err != nil {
+		t.Fatalf("SaveToFile failed: %v", err)
+	}
+}
+
+func TestPDFConverterSections(t *testing.T) {
+	conv := NewHTMLToPDFConverter()
+	sections := []Section{
+		{HTML: `<html><body>
+			<footer><p>Page <span data-field="page"></span></p></footer>
+			<p>Portrait section</p>
+		</body></html>`},
+		{HTML: `<html><head>
+			<meta name="page-size" content="A5 landscape">
+			<meta name="page-margin" content="10mm">
+		</head><body><p>Landscape section</p></body></html>`},
+		{HTML: `<html><body><p>Restarted section</p></body></html>`, Page: PageSetup{PageNumberStart: 1}},
+	}
+
+	if err := conv.ConvertSections(sections); err != nil {
+		t.Fatalf("ConvertSections failed: %v", err)
+	}
+	if n := conv.pdf.PageCount(); n != 3 {
+		t.Fatalf("expected 3 pages, got %d", n)
+	}
+	if w, h, _ := conv.pdf.PageSize(2); w != PageA5.Height || h != PageA5.Width {
+		t.Errorf("expected landscape A5 on page 2, got %vx%v", w, h)
+	}
+	if w, h, _ := conv.pdf.PageSize(3); w != PageA5.Height || h != PageA5.Width {
+		t.Errorf("expected page setup to carry over to page 3, got %vx%v", w, h)
+	}
+	if conv.pageNumber() != 1 {
+		t.Errorf("expected page numbering to restart at 1, got %d", conv.pageNumber())
+	}
+	if conv.section.footers["default"] == nil {
+		t.Error("expected footer to carry over to later sections")
+	}
+
+	tmpFile := filepath.Join(t.TempDir(), "sections.pdf")
+	if err := conv.SaveToFile(tmpFile); err != nil {
+		t.Fatalf("SaveToFile failed: %v", err)
+	}
+}
+
+func TestPDFConverterSectionsInheritedFooter(t *testing.T) {
+	conv := NewHTMLToPDFConverter()
+	long := strings.Repeat("A footer long enough to wrap on a narrow page. ", 4)
+	if err := conv.Convert([]string{`<html><body><footer><p>` + long + `</p></footer><p>Wide section</p></body></html>`}); err != nil {
+		t.Fatalf("Convert failed: %v", err)
+	}
+	prev := conv.section.footers["default"]
+	height := prev.height
+
+	conv.startSectionPDF(&Document{}, PageSetup{Size: PageA5, Margins: &Margins{Top: 10, Right: 40, Bottom: 10, Left: 40}, FooterDistance: 10})
+	next := conv.section.footers["default"]
+	if next == nil || next == prev {
+		t.Fatal("expected the next section to inherit a copy of the footer")
+	}
+	if prev.height != height {
+		t.Errorf("expected the previous footer to keep its height %v, got %v", height, prev.height)
+	}
+	if next.height <= height {
+		t.Errorf("expected the inherited footer to be measured for the narrower page, got %v after %v", next.height, height)
+	}
+}
+
+func TestPDFConverterOptions(t *testing.T) {
+	conv := NewHTMLToPDFConverter(Options{
+		Page:       PageSetup{Size: PageLetter, Orientation: Landscape, Margins: &Margins{Top: 20, Right: 20, Bottom: 20, Left: 20}},
+		FontFamily: "Times New Roman",
+		FontSize:   12,
+	})
+	htmlContents := []string{`<html><head><style>@page { margin: 10mm }</style></head><body><p>Body content</p></body></html>`}
+
+	if err := conv.Convert(htmlContents); err != nil {
+		t.Fatalf("Convert failed: %v", err)
+	}
+	if w, h, _ := conv.pdf.PageSize(1); w != PageLetter.Height || h != PageLetter.Width {
+		t.Errorf("expected landscape Letter, got %vx%v", w, h)
+	}
+	if left, _, _, _ := conv.pdf.GetMargins(); left != 10 {
+		t.Errorf("expected @page margin to override options, got left margin %v", left)
+	}
+	if conv.baseFamily != "Times" || conv.baseSize != 12 {
+		t.Errorf("expected Times 12pt, got %s %vpt", conv.baseFamily, conv.baseSize)
+	}
+
+	tmpFile := filepath.Join(t.TempDir(), "options.pdf")
+	if err := conv.SaveToFile(tmpFile); err != nil {
+		t.Fatalf("SaveToFile failed: %v", err)
+	}
+}
+
+func TestPDFConverterOptionsPageNumberStart(t *testing.T) {
+	conv := NewHTMLToPDFConverter(Options{Page: PageSetup{PageNumberStart: 5}})
+	footer := `<footer><p>Page <span data-field="page"></span></p></footer>`
+	text := pdfText(pdfContent(t, conv, `<p>First</p>`+footer, `<p>Second</p>`))
+
+	for _, want := range []string{"Page 5", "Page 6"} {
+		if !strings.Contains(text, want) {
+			t.Errorf("expected %q in the text, got %q", want, text)
+		}
+	}
+}
+
+// testFontDir returns the directory of the DejaVu fonts shipped with gofpdf,
+// skipping the test when the module cache is not available.
+func testFontDir(t *testing.T) string {
+	t.Helper()
+	modCache := os.Getenv("GOMODCACHE")
+	if modCache == "" {
+		modCache = filepath.Join(build.Default.GOPATH, "pkg", "mod")
+	}
+	dir := filepath.Join(modCache, "github.com", "jung-kurt", "gofpdf@v1.16.2", "font")
+	if _, err := os.Stat(filepath.Join(dir, "DejaVuSansCondensed.ttf")); err != nil {
+		t.Skipf("DejaVu fonts not available: %v", err)
+	}
+	return dir
+}
+
+func TestPDFConverterRegisterFont(t *testing.T) {
+	dir := testFontDir(t)
+	conv := NewHTMLToPDFConverter(Options{FontFamily: "DejaVu Sans"})
+	err := conv.RegisterFont("DejaVu Sans", FontFiles{
+		Regular: filepath.Join(dir, "DejaVuSansCondensed.ttf"),
+		Bold:    filepath.Join(dir, "DejaVuSansCondensed-Bold.ttf"),
+	})
+	if err != nil {
+		t.Fatalf("RegisterFont failed: %v", err)
+	}
+	if conv.baseFamily != "dejavu sans" {
+		t.Errorf("expected registered font to become the default, got %q", conv.baseFamily)
+	}
+
+	htmlContents := []string{`<html><body>
+		<p>Привет, мир! Γειά σου κόσμε!</p>
+		<p><b>Жирный</b> <i>курсив</i> <font face="Times">Times</font></p>
+		<p style="font-family: 'Courier New', monospace">Courier</p>
+		<p align="center">Центр</p>
+	</body></html>`}
+	content := pdfContent(t, conv, htmlContents...)
+
+	if n := strings.Count(content, "/FontFile2"); n != 2 {
+		t.Errorf("expected the regular and bold files to be embedded, got %d font files", n)
+	}
+	text := pdfUnicodeText(content)
+	for _, want := range []string{"Привет, мир!", "Γειά σου κόσμε!", "Жирный", "курсив", "Центр"} {
+		if !strings.Contains(text, want) {
+			t.Errorf("expected %q drawn with the embedded font, got %q", want, text)
+		}
+	}
+}
+
+func TestPDFConverterRegisterFontMissingFile(t *testing.T) {
+	conv := NewHTMLToPDFConverter()
+	if err := conv.RegisterFont("Missing", FontFiles{Regular: "does-not-exist.ttf"}); err == nil {
+		t.Error("expected an error for a missing font file")
+	}
+	if err := conv.RegisterFont("Empty", FontFiles{}); err == nil {
+		t.Error("expected an error without a regular style file")
+	}
+}
+
+func TestPDFConverterFontFallbacks(t *testing.T) {
+	dir := testFontDir(t)
+	conv := NewHTMLToPDFConverter()
+	if err := conv.RegisterFont("DejaVu", FontFiles{Regular: filepath.Join(dir, "DejaVuSansCondensed.ttf")}); err != nil {
+		t.Fatalf("RegisterFont failed: %v", err)
+	}
+	if err := conv.SetFontFallbacks("Missing"); err == nil {
+		t.Error("expected an error for an unregistered fallback font")
+	}
+	if err := conv.SetFontFallbacks("DejaVu"); err != nil {
+		t.Fatalf("SetFontFallbacks failed: %v", err)
+	}
+
+	runs := conv.fontRuns("Hello Привет, мир! world")
+	want := []fontRun{{"Arial", "Hello "}, {"dejavu", "Привет, мир! "}, {"Arial", "world"}}
+	if len(runs) != len(want) {
+		t.Fatalf("fontRuns = %+v, want %+v", runs, want)
+	}
+	for i := range want {
+		if runs[i] != want[i] {
+			t.Errorf("run %d = %+v, want %+v", i, runs[i], want[i])
+		}
+	}
+
+	htmlContents := []string{`<html><body>
+		<p>English, <b>Ελληνικά</b> and Русский in one paragraph.</p>
+		<p align="center">Centered: Привет</p>
+	</body></html>`}
+	if err := conv.Convert(htmlContents); err != nil {
+		t.Fatalf("Convert failed: %v", err)
+	}
+
+	tmpFile := filepath.Join(t.TempDir(), "fallbacks.pdf")
+	if err := conv.SaveToFile(tmpFile); err != nil {
+		t.Fatalf("SaveToFile failed: %v", err)
+	}
+}
+
+func TestPDFConverterRTL(t *testing.T) {
+	dir := testFontDir(t)
+	conv := NewHTMLToPDFConverter(Options{FontFamily: "DejaVu"})
+	if err := conv.RegisterFont("DejaVu", FontFiles{Regular: filepath.Join(dir, "DejaVuSansCondensed.ttf")}); err != nil {
+		t.Fatalf("RegisterFont failed: %v", err)
+	}
+	htmlContents := []string{`<html><body>
+		<h2>שלום עולם</h2>
+		<p>English with an embedded שלום word.</p>
+		<div dir="rtl"><p>שלום <b>עולם</b> 123 and English</p></div>
+	</body></html>`}
+
+	// gofpdf draws characters left to right, so the text must come out in
+	// visual order.
+	text := pdfUnicodeText(pdfContent(t, conv, htmlContents...))
+	for _, want := range []string{
+		"םלוע םולש",
+		"English with an embedded םולש word.",
+		"and English 123 םלוע םולש",
+	} {
+		if !strings.Contains(text, want) {
+			t.Errorf("expected %q in visual order, got %q", want, text)
+		}
+	}
+}
+
+func TestPDFConverterLang(t *testing.T) {
+	conv := NewHTMLToPDFConverter()
+	htmlContents := []string{`<html lang="de-DE"><body><p>Hallo Welt</p></body></html>`}
+
+	if err := conv.Convert(htmlContents); err != nil {
+		t.Fatalf("Convert failed: %v", err)
+	}
+
+	tmpFile := filepath.Join(t.TempDir(), "lang.pdf")
+	if err := conv.SaveToFile(tmpFile); err != nil {
+		t.Fatalf("SaveToFile failed: %v", err)
+	}
+	data, err := os.ReadFile(tmpFile)
+	if err != nil {
+		t.Fatalf("could not read output: %v", err)
+	}
+	if !strings.Contains(string(data), "/Lang (de-DE)") {
+		t.Error("expected /Lang (de-DE) in the document catalog")
+	}
+
+	// startxref must still point at the cross-reference table.
+	s := string(data)
+	i := strings.LastIndex(s, "startxref\n")
+	var offset int
+	if _, err := fmt.Sscanf(s[i+len("startxref\n"):], "%d", &offset); err != nil {
+		t.Fatalf("could not read startxref: %v", err)
+	}
+	if !strings.HasPrefix(s[offset:], "xref") {
+		t.Errorf("startxref %d does not point at the xref table", offset)
+	}
+}
+
+func TestPDFConverterMetadata(t *testing.T) {
+	conv := NewHTMLToPDFConverter(Options{Metadata: Metadata{Creator: "html2docx"}})
+	htmlContents := []string{`<html><head>
+		<title>Quarterly Report</title>
+		<meta name="author" content="Jane Doe">
+		<meta name="description" content="Results for Q3">
+	</head><body><p>Body text</p></body></html>`}
+
+	if err := conv.Convert(htmlContents); err != nil {
+		t.Fatalf("Convert failed: %v", err)
+	}
+
+	tmpFile := filepath.Join(t.TempDir(), "metadata.pdf")
+	if err := conv.SaveToFile(tmpFile); err != nil {
+		t.Fatalf("SaveToFile failed: %v", err)
+	}
+	data, err := os.ReadFile(tmpFile)
+	if err != nil {
+		t.Fatalf("could not read output: %v", err)
+	}
+	for _, key := range []string{"/Title", "/Author", "/Subject", "/Creator"} {
+		if !strings.Contains(string(data), key+" (") {
+			t.Errorf("expected %s in the document info", key)
+		}
+	}
+}
+
+func TestPDFConverterBytes(t *testing.T) {
+	conv := NewHTMLToPDFConverter()
+	if err := conv.Convert([]string{`<html lang="en"><body><p>Streamed</p></body></html>`}); err != nil {
+		t.Fatalf("Convert failed: %v", err)
+	}
+
+	data, err := conv.Bytes()
+	if err != nil {
+		t.Fatalf("Bytes failed: %v", err)
+	}
+	if !bytes.HasPrefix(data, []byte("%PDF-")) {
+		t.Error("expected a PDF header")
+	}
+	var buf bytes.Buffer
+	n, err := conv.WriteTo(&buf)
+	if err != nil {
+		t.Fatalf("WriteTo failed: %v", err)
+	}
+	if n != int64(len(data)) || !bytes.Contains(buf.Bytes(), []byte("/Lang (en)")) {
+		t.Errorf("expected WriteTo to write the same %d bytes as Bytes, got %d", len(data), n)
+	}
+}
+
+func TestPDFConverterConvertAfterBytes(t *testing.T) {
+	conv := NewHTMLToPDFConverter()
+	if err := conv.Convert([]string{"<p>First</p>"}); err != nil {
+		t.Fatalf("Convert failed: %v", err)
+	}
+	data, err := conv.Bytes()
+	if err != nil {
+		t.Fatalf("Bytes failed: %v", err)
+	}
+
+	if err := conv.Convert([]string{"<p>Second</p>"}); !errors.Is(err, ErrPDFWritten) {
+		t.Fatalf("expected ErrPDFWritten, got %v", err)
+	}
+	again, err := conv.Bytes()
+	if err != nil {
+		t.Fatalf("Bytes failed: %v", err)
+	}
+	if !bytes.Equal(again, data) {
+		t.Error("expected the written document to be unchanged")
+	}
+}
+
+func TestPDFConverterOptionsFonts(t *testing.T) {
+	dir := testFontDir(t)
+	conv := NewHTMLToPDFConverter(Options{
+		FontFamily: "DejaVu Sans",
+		Fonts:      map[string]FontFiles{"DejaVu Sans": {Regular: filepath.Join(dir, "DejaVuSansCondensed.ttf")}},
+	})
+	if conv.baseFamily != "dejavu sans" {
+		t.Errorf("expected the font in the options to become the default, got %q", conv.baseFamily)
+	}
+	if err := conv.Convert([]string{"<p>Привет</p>"}); err != nil {
+		t.Fatalf("Convert failed: %v", err)
+	}
+
+	conv = NewHTMLToPDFConverter(Options{Fonts: map[string]FontFiles{"Missing": {Regular: "does-not-exist.ttf"}}})
+	if err := conv.Convert([]string{"<p>x</p>"}); err == nil || !strings.Contains(err.Error(), "does-not-exist.ttf") {
+		t.Errorf("expected the font error from Convert, got %v", err)
+	}
+}
+
+func TestPDFConverterClassedFooter(t *testing.T) {
+	conv := NewHTMLToPDFConverter(Options{ClassStyles: map[string]Style{
+		"legal": {Italic: true, Color: "#FF0000"},
+	}})
+	content := pdfContent(t, conv, `<html><body>
+		<p>Content</p>
+		<footer class="legal"><p>Confidential</p></footer>
+	</body></html>`)
+
+	if !strings.Contains(content, "/BaseFont /Helvetica-Oblique") {
+		t.Error("expected the footer's class to make it italic")
+	}
+	if !strings.Contains(content, "1.000 0.000 0.000 rg BT") {
+		t.Error("expected the footer's class to color it red")
+	}
+}
@@ -0,0 +1,165 @@
+package converter
+
+import (
+	"fmt"
+	"strings"
+
+	"baliance.com/gooxml/document"
+	"baliance.com/gooxml/schema/soo/wml"
+	"github.com/jung-kurt/gofpdf"
+	"golang.org/x/net/html"
+)
+
+// DocxHandler renders an element into a DOCX document in place of the
+// built-in handling. A returned error stops the conversion.
+type DocxHandler func(n *html.Node, ctx *DocxContext) error
+
+// PDFHandler renders an element into a PDF in place of the built-in handling.
+// A returned error stops the conversion.
+type PDFHandler func(n *html.Node, ctx *PDFContext) error
+
+// MarkdownHandler renders an element as Markdown in place of the built-in
+// handling. A returned error stops the conversion.
+type MarkdownHandler func(n *html.Node, ctx *MarkdownContext) error
+
+// DocxContext is the rendering state passed to a DocxHandler.
+type DocxContext struct {
+	Document  *document.Document
+	Paragraph *document.Paragraph // paragraph inline content goes into, nil between blocks
+	Container interface{}         // document.Header, document.Footer or document.Cell, nil for the body
+	Element   *Element            // the element in the document model
+
+	c     *HTMLToDocxConverter
+	align wml.ST_Jc
+}
+
+// Default renders the element with the converter's built-in handling.
+func (x *DocxContext) Default() {
+	x.c.bypass = x.Element
+	x.c.walk(x.Element, x.Paragraph, x.Container, x.align)
+}
+
+// Children renders the element's children with the built-in handling.
+func (x *DocxContext) Children() {
+	x.c.processChildren(x.Element, x.Paragraph, x.Container, x.align)
+}
+
+// AddParagraph starts a new paragraph in the current container and makes it
+// the current paragraph.
+func (x *DocxContext) AddParagraph() document.Paragraph {
+	p := x.c.createParagraph(x.Container)
+	x.c.setDirection(p, x.Element.TextContent(), x.align)
+	x.Paragraph = &p
+	return p
+}
+
+// AddText adds a run of text to the current paragraph, starting one if there
+// is none, with the language and direction of the surrounding content.
+func (x *DocxContext) AddText(text string) document.Run {
+	if x.Paragraph == nil {
+		x.AddParagraph()
+	}
+	return x.c.addText(x.Paragraph, text)
+}
+
+// PDFContext is the rendering state passed to a PDFHandler.
+type PDFContext struct {
+	PDF     *gofpdf.Fpdf
+	Element *Element // the element in the document model
+
+	c *HTMLToPDFConverter
+}
+
+// Default renders the element with the converter's built-in handling.
+func (x *PDFContext) Default() {
+	x.c.bypass = x.Element
+	x.c.walkPDF(x.Element)
+}
+
+// Children renders the element's children with the built-in handling.
+func (x *PDFContext) Children() {
+	x.c.processChildrenPDF(x.Element)
+}
+
+// WriteText writes text in the current font, alignment and direction, with
+// font fallbacks applied.
+func (x *PDFContext) WriteText(text string) {
+	x.c.writeText(text)
+}
+
+// Flush ends any pending aligned line, so the handler can draw directly on
+// the PDF at the current position.
+func (x *PDFContext) Flush() {
+	x.c.flushAligned()
+}
+
+// LineHeight returns the height of a line of text in the current font, in mm.
+func (x *PDFContext) LineHeight() float64 {
+	return x.c.lineHeight()
+}
+
+// MarkdownContext is the rendering state passed to a MarkdownHandler.
+type MarkdownContext struct {
+	Builder *strings.Builder // Markdown written so far
+	Element *Element         // the element in the document model
+
+	c *HTMLToMarkdownConverter
+}
+
+// Default renders the element with the converter's built-in handling.
+func (x *MarkdownContext) Default() {
+	x.c.bypass = x.Element
+	x.c.walkMD(x.Element)
+}
+
+// Children renders the element's children with the built-in handling.
+func (x *MarkdownContext) Children() {
+	x.c.processChildrenMD(x.Element)
+}
+
+// Handle registers h to render elements whose EffectiveNodeType is nodeType,
+// replacing the built-in handling and any earlier handler. A nil h removes
+// the handler.
+func (c *HTMLToDocxConverter) Handle(nodeType string, h DocxHandler) {
+	if c.handlers == nil {
+		c.handlers = map[string]DocxHandler{}
+	}
+	if h == nil {
+		delete(c.handlers, nodeType)
+		return
+	}
+	c.handlers[nodeType] = h
+}
+
+// Handle registers h to render elements whose EffectiveNodeType is nodeType,
+// replacing the built-in handling and any earlier handler. A nil h removes
+// the handler.
+func (c *HTMLToPDFConverter) Handle(nodeType string, h PDFHandler) {
+	if c.handlers == nil {
+		c.handlers = map[string]PDFHandler{}
+	}
+	if h == nil {
+		delete(c.handlers, nodeType)
+		return
+	}
+	c.handlers[nodeType] = h
+}
+
+// Handle registers h to render elements whose EffectiveNodeType is nodeType,
+// replacing the built-in handling and any earlier handler. A nil h removes
+// the handler.
+func (c *HTMLToMarkdownConverter) Handle(nodeType string, h MarkdownHandler) {
+	if c.handlers == nil {
+		c.handlers = map[string]MarkdownHandler{}
+	}
+	if h == nil {
+		delete(c.handlers, nodeType)
+		return
+	}
+	c.handlers[nodeType] = h
+}
+
+// handlerError wraps an error returned by a custom element handler.
+func handlerError(nodeType string, err error) error {
+	return fmt.Errorf("failed to render %s element: %w", nodeType, err)
+}
@@ -0,0 +1,106 @@
+package converter
+
+import (
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"golang.org/x/net/html"
+)
+
+const handlersHTML = `<html><body>
+	<callout type="warning"><p>Check the totals.</p></callout>
+	<signature-block name="Jane Doe"></signature-block>
+	<p>See <span data-slate-type="mention">Bob</span>.</p>
+</body></html>`
+
+func TestMarkdownConverterHandlers(t *testing.T) {
+	conv := NewHTMLToMarkdownConverter()
+	conv.Handle("callout", func(n *html.Node, ctx *MarkdownContext) error {
+		ctx.Builder.WriteString("\n> **" + GetAttrValue(n.Attr, "type") + ":** ")
+		ctx.Children()
+		return nil
+	})
+	conv.Handle("signature-block", func(n *html.Node, ctx *MarkdownContext) error {
+		ctx.Builder.WriteString("Signed: " + GetAttrValue(n.Attr, "name") + "\n\n")
+		return nil
+	})
+	mentions := 0
+	conv.Handle("mention", func(n *html.Node, ctx *MarkdownContext) error {
+		mentions++
+		ctx.Builder.WriteString("@")
+		ctx.Default()
+		return nil
+	})
+
+	md, err := conv.Convert([]string{handlersHTML})
+	if err != nil {
+		t.Fatalf("Convert failed: %v", err)
+	}
+	for _, want := range []string{"> **warning:** Check the totals.", "Signed: Jane Doe", "See @Bob."} {
+		if !strings.Contains(md, want) {
+			t.Errorf("expected %q in output, got: %s", want, md)
+		}
+	}
+	if mentions != 1 {
+		t.Errorf("expected the mention handler to run once, ran %d times", mentions)
+	}
+}
+
+func TestDocxConverterHandlers(t *testing.T) {
+	conv := NewHTMLToDocxConverter()
+	var names []string
+	conv.Handle("signature-block", func(n *html.Node, ctx *DocxContext) error {
+		names = append(names, GetAttrValue(n.Attr, "name"))
+		ctx.AddParagraph()
+		ctx.AddText("Signed: " + GetAttrValue(n.Attr, "name")).Properties().SetItalic(true)
+		return nil
+	})
+	conv.Handle("callout", func(n *html.Node, ctx *DocxContext) error {
+		ctx.Default()
+		return nil
+	})
+
+	if err := conv.Convert([]string{handlersHTML}); err != nil {
+		t.Fatalf("Convert failed: %v", err)
+	}
+	if len(names) != 1 || names[0] != "Jane Doe" {
+		t.Errorf("expected the signature handler to run once, got %v", names)
+	}
+
+	tmpFile := filepath.Join(t.TempDir(), "handlers.docx")
+	if err := conv.SaveToFile(tmpFile); err != nil {
+		t.Fatalf("SaveToFile failed: %v", err)
+	}
+}
+
+func TestPDFConverterHandlers(t *testing.T) {
+	conv := NewHTMLToPDFConverter()
+	conv.Handle("signature-block", func(n *html.Node, ctx *PDFContext) error {
+		ctx.Flush()
+		y := ctx.PDF.GetY() + ctx.LineHeight()
+		ctx.PDF.Line(20, y, 80, y)
+		ctx.PDF.Ln(ctx.LineHeight() * 1.5)
+		ctx.WriteText(GetAttrValue(n.Attr, "name"))
+		return nil
+	})
+	errStop := errors.New("no mentions allowed")
+	conv.Handle("mention", func(n *html.Node, ctx *PDFContext) error {
+		return errStop
+	})
+
+	err := conv.Convert([]string{handlersHTML})
+	if !errors.Is(err, errStop) {
+		t.Fatalf("expected the handler error, got %v", err)
+	}
+
+	conv.Handle("mention", nil)
+	if err := conv.Convert([]string{handlersHTML}); err != nil {
+		t.Fatalf("Convert failed after removing the handler: %v", err)
+	}
+	tmpFile := filepath.Join(t.TempDir(), "handlers.pdf")
+	if err := conv.SaveToFile(tmpFile); err != nil {
+		t.Fatalf("SaveToFile failed: %v", err)
+	}
+}
@@ -1,10 +1,13 @@
 package converter
 
 import (
+	"fmt"
+	"io"
 	"strconv"
 	"strings"
 
 	"golang.org/x/net/html"
+	"golang.org/x/net/html/charset"
 )
 
 // GetAttrMap converts HTML attributes to a map for easy lookup.
@@ -26,6 +29,18 @@ func GetAttrValue(attrs []html.Attribute, key string) string {
 	return ""
 }
 
+// StyleProperty returns the value of a CSS property from an element's inline
+// style attribute, or "" if it is not set.
+func StyleProperty(n *html.Node, name string) string {
+	for _, decl := range strings.Split(GetAttrValue(n.Attr, "style"), ";") {
+		key, value, ok := strings.Cut(decl, ":")
+		if ok && strings.EqualFold(strings.TrimSpace(key), name) {
+			return strings.TrimSpace(value)
+		}
+	}
+	return ""
+}
+
 // ExtractText recursively extracts all text content from an HTML node.
 func ExtractText(n *html.Node) string {
 	if n.Type == html.TextNode {
@@ -81,6 +96,51 @@ func EffectiveNodeType(n *html.Node) string {
 	return n.Data
 }
 
+// PageField returns the page-number field a placeholder element stands for,
+// such as <span data-field="page"> or <span data-field="pages">.
+// It returns "page", "pages" or an empty string if the node is not a field.
+func PageField(n *html.Node) string {
+	if n.Type != html.ElementNode {
+		return ""
+	}
+	switch strings.ToLower(strings.TrimSpace(GetAttrValue(n.Attr, "data-field"))) {
+	case "page":
+		return "page"
+	case "pages", "numpages":
+		return "pages"
+	}
+	return ""
+}
+
+// PageVariant returns which pages a <header> or <footer> applies to, from its
+// data-page attribute: "first", "even" or "default" (odd pages, or every page
+// when no other variant is defined).
+func PageVariant(n *html.Node) string {
+	switch strings.ToLower(strings.TrimSpace(GetAttrValue(n.Attr, "data-page"))) {
+	case "first":
+		return "first"
+	case "even":
+		return "even"
+	}
+	return "default"
+}
+
+// ReadHTML reads an HTML document and decodes it to UTF-8. The encoding is
+// detected from a byte order mark, then from contentType (an HTTP-style
+// Content-Type such as "text/html; charset=Shift_JIS", may be empty), then
+// from a <meta charset> tag, falling back to Windows-1252.
+func ReadHTML(r io.Reader, contentType string) (string, error) {
+	decoded, err := charset.NewReader(r, contentType)
+	if err != nil {
+		return "", fmt.Errorf("failed to detect HTML encoding: %w", err)
+	}
+	data, err := io.ReadAll(decoded)
+	if err != nil {
+		return "", fmt.Errorf("failed to read HTML: %w", err)
+	}
+	return string(data), nil
+}
+
 // UnescapeUnicodeHTML unescapes JSON unicode sequences back to HTML characters.
 // This is critical for supporting tools that export DOM structures as JSON strings (like Slate.js raw data).
 func UnescapeUnicodeHTML(s string) string {
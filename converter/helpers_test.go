@@ -5,6 +5,8 @@ import (
 	"testing"
 
 	"golang.org/x/net/html"
+	"golang.org/x/text/encoding/japanese"
+	"golang.org/x/text/transform"
 )
 
 func TestGetAttrMap(t *testing.T) {
@@ -141,3 +143,100 @@ func TestParseHexToRGB(t *testing.T) {
 		}
 	}
 }
+
+func TestPageField(t *testing.T) {
+	tests := []struct {
+		html     string
+		expected string
+	}{
+		{`<span data-field="page"></span>`, "page"},
+		{`<span data-field="PAGES"></span>`, "pages"},
+		{`<span data-field="numpages"></span>`, "pages"},
+		{`<span data-field="date"></span>`, ""},
+		{`<span>plain</span>`, ""},
+	}
+
+	for _, tc := range tests {
+		doc, _ := html.Parse(strings.NewReader("<p>" + tc.html + "</p>"))
+		var span *html.Node
+		var find func(*html.Node)
+		find = func(n *html.Node) {
+			if n.Type == html.ElementNode && n.Data == "span" {
+				span = n
+				return
+			}
+			for c := n.FirstChild; c != nil; c = c.NextSibling {
+				find(c)
+			}
+		}
+		find(doc)
+		if span == nil {
+			t.Fatalf("could not find span in %q", tc.html)
+		}
+		if got := PageField(span); got != tc.expected {
+			t.Errorf("PageField(%q) = %q, want %q", tc.html, got, tc.expected)
+		}
+	}
+}
+
+func TestPageVariant(t *testing.T) {
+	tests := []struct {
+		attrs    []html.Attribute
+		expected string
+	}{
+		{[]html.Attribute{{Key: "data-page", Val: "first"}}, "first"},
+		{[]html.Attribute{{Key: "data-page", Val: "Even"}}, "even"},
+		{[]html.Attribute{{Key: "data-page", Val: "odd"}}, "default"},
+		{nil, "default"},
+	}
+
+	for _, tc := range tests {
+		n := &html.Node{Type: html.ElementNode, Data: "header", Attr: tc.attrs}
+		if got := PageVariant(n); got != tc.expected {
+			t.Errorf("PageVariant(%v) = %q, want %q", tc.attrs, got, tc.expected)
+		}
+	}
+}
+
+func TestStyleProperty(t *testing.T) {
+	n := &html.Node{Type: html.ElementNode, Data: "span", Attr: []html.Attribute{
+		{Key: "style", Val: "color: red; Font-Family: 'DejaVu Sans', sans-serif ;"},
+	}}
+
+	if got := StyleProperty(n, "font-family"); got != "'DejaVu Sans', sans-serif" {
+		t.Errorf("StyleProperty(font-family) = %q", got)
+	}
+	if got := StyleProperty(n, "font-size"); got != "" {
+		t.Errorf("StyleProperty(font-size) = %q, want empty", got)
+	}
+}
+
+func TestReadHTML(t *testing.T) {
+	sjis, _, err := transform.String(japanese.ShiftJIS.NewEncoder(), "<p>こんにちは</p>")
+	if err != nil {
+		t.Fatalf("could not encode Shift-JIS: %v", err)
+	}
+	tests := []struct {
+		name        string
+		input       string
+		contentType string
+		expected    string
+	}{
+		{"meta charset", "<meta charset=\"windows-1252\"><p>caf\xe9 \x93quoted\x94</p>", "", "café “quoted”"},
+		{"content type", sjis, "text/html; charset=Shift_JIS", "こんにちは"},
+		{"byte order mark", "\xef\xbb\xbf<p>naïve</p>", "text/html; charset=iso-8859-1", "naïve"},
+		{"utf-8 meta", "<meta charset=\"utf-8\"><p>Grüße</p>", "", "Grüße"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := ReadHTML(strings.NewReader(tc.input), tc.contentType)
+			if err != nil {
+				t.Fatalf("ReadHTML failed: %v", err)
+			}
+			if !strings.Contains(got, tc.expected) {
+				t.Errorf("expected %q in %q", tc.expected, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,131 @@
+package converter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"golang.org/x/net/html"
+)
+
+// Limits bounds the resources a conversion may use, for HTML from untrusted
+// sources. Zero fields are unlimited.
+type Limits struct {
+	MaxDepth      int   // deepest nesting of the parsed HTML tree
+	MaxNodes      int   // parsed HTML nodes across all inputs of a conversion
+	MaxPages      int   // PDF only: pages of output
+	MaxImageBytes int64 // PDF only: size of a single image
+}
+
+// ErrLimitExceeded is matched by every LimitError, for use with errors.Is.
+var ErrLimitExceeded = errors.New("limit exceeded")
+
+// LimitError reports that a conversion was aborted for exceeding one of its
+// Limits.
+type LimitError struct {
+	Limit string // "depth", "nodes", "pages" or "image bytes"
+	Max   int64
+}
+
+func (e *LimitError) Error() string {
+	return fmt.Sprintf("%s limit of %d exceeded", e.Limit, e.Max)
+}
+
+// Is reports whether target is ErrLimitExceeded.
+func (e *LimitError) Is(target error) bool {
+	return target == ErrLimitExceeded
+}
+
+// guard enforces cancellation, limits and strict mode during a conversion,
+// and collects its warnings. The walk functions call enter for every node and
+// stop once an error is recorded.
+type guard struct {
+	ctx      context.Context
+	limits   Limits
+	strict   bool
+	nodes    int
+	err      error
+	warnings []Warning
+	warned   map[*html.Node]bool // elements already warned about, as headers render on every page
+}
+
+// start begins a conversion under ctx.
+func (g *guard) start(ctx context.Context) {
+	g.ctx = ctx
+	g.nodes = 0
+	g.err = nil
+}
+
+// enter reports whether the walk may continue, recording the context's error
+// once it is canceled.
+func (g *guard) enter() bool {
+	if g.err != nil {
+		return false
+	}
+	if g.ctx != nil {
+		if err := g.ctx.Err(); err != nil {
+			g.err = fmt.Errorf("conversion stopped: %w", err)
+			return false
+		}
+	}
+	return true
+}
+
+// fail records err as the reason the conversion stopped, keeping the first.
+func (g *guard) fail(err error) {
+	if g.err == nil {
+		g.err = err
+	}
+}
+
+// warn records a warning for element n, failing the conversion in strict
+// mode. An element is warned about once however often it is rendered.
+func (g *guard) warn(n *html.Node, reason string) {
+	if g.warned[n] {
+		return
+	}
+	if g.warned == nil {
+		g.warned = map[*html.Node]bool{}
+	}
+	g.warned[n] = true
+	w := Warning{Element: n.Data, Path: nodePath(n), Reason: reason}
+	g.warnings = append(g.warnings, w)
+	if g.strict {
+		g.fail(&WarningError{Warning: w})
+	}
+}
+
+// check counts the nodes of a parsed document and measures its depth before
+// it is walked, so documents over the limits are rejected before the
+// recursive walk can exhaust the stack. It does not recurse itself.
+func (g *guard) check(root *html.Node) error {
+	if !g.enter() {
+		return g.err
+	}
+	depth := 0
+	for n := root; n != nil; {
+		g.nodes++
+		if max := g.limits.MaxNodes; max > 0 && g.nodes > max {
+			g.fail(&LimitError{Limit: "nodes", Max: int64(max)})
+			return g.err
+		}
+		if max := g.limits.MaxDepth; max > 0 && depth > max {
+			g.fail(&LimitError{Limit: "depth", Max: int64(max)})
+			return g.err
+		}
+		if n.FirstChild != nil {
+			n = n.FirstChild
+			depth++
+			continue
+		}
+		for n != root && n.NextSibling == nil {
+			n = n.Parent
+			depth--
+		}
+		if n == root {
+			break
+		}
+		n = n.NextSibling
+	}
+	return nil
+}
@@ -0,0 +1,69 @@
+package converter
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestLimitsDepthAndNodes(t *testing.T) {
+	deep := []string{"<html><body>" + strings.Repeat("<div>", 200) + "text" + strings.Repeat("</div>", 200) + "</body></html>"}
+	wide := []string{"<html><body>" + strings.Repeat("<p>x</p>", 200) + "</body></html>"}
+
+	tests := []struct {
+		name   string
+		limits Limits
+		input  []string
+		limit  string
+	}{
+		{"depth", Limits{MaxDepth: 50}, deep, "depth"},
+		{"nodes", Limits{MaxNodes: 100}, wide, "nodes"},
+	}
+
+	for _, tc := range tests {
+		for _, f := range Formats() {
+			conv := f.New(Options{Limits: tc.limits})
+			err := conv.ConvertSections(HTMLSections(tc.input))
+			var le *LimitError
+			if !errors.Is(err, ErrLimitExceeded) || !errors.As(err, &le) || le.Limit != tc.limit {
+				t.Errorf("%s/%s: expected %s limit error, got %v", tc.name, f.Name, tc.limit, err)
+			}
+		}
+	}
+
+	conv := NewHTMLToDocxConverter(Options{Limits: Limits{MaxDepth: 500, MaxNodes: 1000}})
+	if err := conv.Convert(deep); err != nil {
+		t.Errorf("expected document within limits to convert, got %v", err)
+	}
+}
+
+func TestConvertContextCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	input := HTMLSections([]string{`<html><body><p>Never converted</p></body></html>`})
+
+	for _, f := range Formats() {
+		err := f.New().ConvertSectionsContext(ctx, input)
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("%s: expected context.Canceled, got %v", f.Name, err)
+		}
+	}
+}
+
+func TestPDFLimits(t *testing.T) {
+	conv := NewHTMLToPDFConverter(Options{Limits: Limits{MaxPages: 2}})
+	err := conv.Convert([]string{"<html><body>" + strings.Repeat("<p>Body paragraph that fills the page.</p>", 300) + "</body></html>"})
+	var le *LimitError
+	if !errors.As(err, &le) || le.Limit != "pages" {
+		t.Errorf("expected pages limit error, got %v", err)
+	}
+
+	payload := base64.StdEncoding.EncodeToString(make([]byte, 4096))
+	conv = NewHTMLToPDFConverter(Options{Limits: Limits{MaxImageBytes: 1024}})
+	err = conv.Convert([]string{`<html><body><img src="data:image/png;base64,` + payload + `"></body></html>`})
+	if !errors.As(err, &le) || le.Limit != "image bytes" {
+		t.Errorf("expected image bytes limit error, got %v", err)
+	}
+}
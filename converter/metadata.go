@@ -0,0 +1,87 @@
+package converter
+
+import (
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// Metadata describes a document, recorded in DOCX core and app properties and
+// in the PDF Info dictionary.
+type Metadata struct {
+	Title       string
+	Author      string
+	Subject     string
+	Description string
+	Keywords    []string
+	Creator     string // application that created the document
+}
+
+// Merge returns m with its empty fields taken from base.
+func (m Metadata) Merge(base Metadata) Metadata {
+	if m.Title == "" {
+		m.Title = base.Title
+	}
+	if m.Author == "" {
+		m.Author = base.Author
+	}
+	if m.Subject == "" {
+		m.Subject = base.Subject
+	}
+	if m.Description == "" {
+		m.Description = base.Description
+	}
+	if len(m.Keywords) == 0 {
+		m.Keywords = base.Keywords
+	}
+	if m.Creator == "" {
+		m.Creator = base.Creator
+	}
+	return m
+}
+
+// ParseMetadata reads document metadata from the <title> element and from
+// <meta name="author|description|keywords|subject|generator"> tags of an
+// HTML document. Keywords are comma separated.
+func ParseMetadata(root *html.Node) Metadata {
+	var m Metadata
+	var walk func(*html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.ElementNode {
+			switch n.Data {
+			case "body", "svg":
+				return
+			case "title":
+				if m.Title == "" {
+					m.Title = strings.TrimSpace(CollapseWhitespace(ExtractText(n)))
+				}
+				return
+			case "meta":
+				attrs := GetAttrMap(n.Attr)
+				content := strings.TrimSpace(attrs["content"])
+				switch strings.ToLower(attrs["name"]) {
+				case "author":
+					m.Author = content
+				case "description":
+					m.Description = content
+				case "subject":
+					m.Subject = content
+				case "generator":
+					m.Creator = content
+				case "keywords":
+					m.Keywords = nil
+					for _, k := range strings.Split(content, ",") {
+						if k = strings.TrimSpace(k); k != "" {
+							m.Keywords = append(m.Keywords, k)
+						}
+					}
+				}
+			}
+		}
+		for ch := n.FirstChild; ch != nil; ch = ch.NextSibling {
+			walk(ch)
+		}
+	}
+	walk(root)
+	return m
+}
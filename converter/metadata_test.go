@@ -0,0 +1,41 @@
+package converter
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"golang.org/x/net/html"
+)
+
+func TestParseMetadata(t *testing.T) {
+	doc, _ := html.Parse(strings.NewReader(`<html><head>
+		<title>  Annual
+		Report </title>
+		<meta name="Author" content="Jane Doe">
+		<meta name="description" content="Results for the year">
+		<meta name="subject" content="Finance">
+		<meta name="keywords" content="finance, annual , ,report">
+		<meta name="generator" content="Report Builder">
+	</head><body><svg><title>Chart</title></svg></body></html>`))
+
+	got := ParseMetadata(doc)
+	want := Metadata{
+		Title:       "Annual Report",
+		Author:      "Jane Doe",
+		Subject:     "Finance",
+		Description: "Results for the year",
+		Keywords:    []string{"finance", "annual", "report"},
+		Creator:     "Report Builder",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ParseMetadata = %+v, want %+v", got, want)
+	}
+}
+
+func TestMetadataMerge(t *testing.T) {
+	m := Metadata{Title: "Explicit"}.Merge(Metadata{Title: "From HTML", Author: "Jane Doe"})
+	if m.Title != "Explicit" || m.Author != "Jane Doe" {
+		t.Errorf("Merge = %+v", m)
+	}
+}
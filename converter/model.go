@@ -0,0 +1,501 @@
+package converter
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// Kind is the type of an Element in the document model.
+type Kind int
+
+const (
+	KindText        Kind = iota // a run of text, in Element.Text
+	KindContainer               // a block grouping other blocks: div, section, body, ...
+	KindParagraph               // a paragraph of inline content
+	KindHeading                 // a heading of Element.Level 1 to 6
+	KindSpan                    // inline content with the element's Style
+	KindLink                    // a hyperlink to Element.Href
+	KindBreak                   // a line break
+	KindRule                    // a horizontal rule
+	KindImage                   // an image from Element.Src
+	KindList                    // a list of KindListItem children
+	KindListItem                // an item of a list
+	KindTable                   // a table of KindTableRow children
+	KindTableRow                // a table row of KindTableCell children
+	KindTableCell               // a table cell
+	KindPre                     // preformatted text
+	KindQuote                   // a block quotation
+	KindField                   // a page-number field named by Element.Field
+	KindUnsupported             // content no converter renders, see Element.Reason
+	KindRegion                  // a <header> or <footer> inside the content, as its only child
+)
+
+var kindNames = [...]string{
+	"text", "container", "paragraph", "heading", "span", "link", "break", "rule", "image",
+	"list", "list item", "table", "table row", "table cell", "pre", "quote", "field", "unsupported",
+	"region",
+}
+
+// String returns the name of the kind, e.g. "paragraph".
+func (k Kind) String() string {
+	if k < 0 || int(k) >= len(kindNames) {
+		return "Kind(" + strconv.Itoa(int(k)) + ")"
+	}
+	return kindNames[k]
+}
+
+// Style is inline formatting an element applies to its content.
+type Style struct {
+	Bold       bool
+	Italic     bool
+	Underline  bool
+	Code       bool    // monospaced
+	Color      string  // text color as a hex string, e.g. "#FF0000"
+	FontFamily string  // CSS font-family list or <font face>
+	FontSize   float64 // in points
+}
+
+// merge returns s with the formatting set in inner applied on top.
+func (s Style) merge(inner Style) Style {
+	s.Bold = s.Bold || inner.Bold
+	s.Italic = s.Italic || inner.Italic
+	s.Underline = s.Underline || inner.Underline
+	s.Code = s.Code || inner.Code
+	if inner.Color != "" {
+		s.Color = inner.Color
+	}
+	if inner.FontFamily != "" {
+		s.FontFamily = inner.FontFamily
+	}
+	if inner.FontSize > 0 {
+		s.FontSize = inner.FontSize
+	}
+	return s
+}
+
+// Element is a node of the document model the converters render from. The
+// fields after Children apply to the kinds named in their comments.
+type Element struct {
+	Kind     Kind
+	Type     string     // EffectiveNodeType of the source element, "" for text
+	Source   *html.Node // the HTML node the element was built from
+	Children []*Element
+
+	Style Style
+	Align string // "left", "center" or "right" from the align attribute
+	Dir   string // "rtl" or "ltr" from the dir attribute
+	Lang  string // lang attribute
+
+	Text        string  // KindText
+	Implicit    bool    // KindParagraph: wraps loose inline content, with no <p> in the HTML
+	Level       int     // KindHeading
+	Href        string  // KindLink
+	Src, Alt    string  // KindImage
+	ImageWidth  float64 // KindImage: width attribute in CSS pixels, 0 if unset
+	ImageHeight float64 // KindImage: height attribute in CSS pixels, 0 if unset
+	RuleSize    int     // KindRule: size attribute, 0 if unset
+	Ordered     bool    // KindList
+	Border      bool    // KindTable: has a border, i.e. is a data table rather than layout
+	Width       string  // KindTable: width attribute, e.g. "80%"
+	Header      bool    // KindTableRow, KindTableCell: is or holds a <th>
+	Background  string  // KindTableCell: bgcolor of the cell or its row
+	Field       string  // KindField: "page" or "pages"
+	Variant     string  // header and footer regions: PageVariant
+	Reason      string  // KindUnsupported: why the content is dropped
+}
+
+// IsBlock reports whether the element starts its own block rather than
+// flowing inline with the text around it.
+func (e *Element) IsBlock() bool {
+	switch e.Kind {
+	case KindContainer, KindParagraph, KindHeading, KindRule, KindList, KindTable, KindPre, KindQuote, KindRegion:
+		return true
+	}
+	return false
+}
+
+// TextContent returns the text of the element and all its descendants.
+func (e *Element) TextContent() string {
+	if e.Kind == KindText {
+		return e.Text
+	}
+	var sb strings.Builder
+	for _, ch := range e.Children {
+		sb.WriteString(ch.TextContent())
+	}
+	return sb.String()
+}
+
+// hasContent reports whether the element renders anything visible: text
+// other than whitespace, an image, a field or a line break.
+func (e *Element) hasContent() bool {
+	switch e.Kind {
+	case KindText:
+		return strings.TrimSpace(e.Text) != ""
+	case KindImage, KindField, KindBreak, KindRule:
+		return true
+	}
+	for _, ch := range e.Children {
+		if ch.hasContent() {
+			return true
+		}
+	}
+	return false
+}
+
+// Document is an HTML document interpreted as the document model: the body
+// as a tree of elements, plus the page headers and footers and the settings
+// given in the document's <head>.
+type Document struct {
+	Body     *Element
+	Headers  map[string][]*Element // <header> regions keyed by PageVariant
+	Footers  map[string][]*Element // <footer> regions keyed by PageVariant
+	Page     PageSetup             // from <meta> tags and CSS @page rules
+	Metadata Metadata              // from <title> and <meta> tags
+	Lang     string                // lang attribute of <html>
+}
+
+// BuildDocument interprets a parsed HTML document as the document model.
+// Loose inline content between blocks is wrapped in implicit paragraphs, and
+// <header> and <footer> elements are moved out of the body into regions. Those
+// not directly in <body> also stay in place as a KindRegion, for formats
+// without pages.
+func BuildDocument(root *html.Node) *Document {
+	d := &Document{
+		Headers:  map[string][]*Element{},
+		Footers:  map[string][]*Element{},
+		Page:     ParsePageSetup(root),
+		Metadata: ParseMetadata(root),
+		Lang:     documentLang(root),
+	}
+	b := &modelBuilder{doc: d}
+	d.Body = &Element{Kind: KindContainer, Source: root, Children: b.children(root, true)}
+	return d
+}
+
+// parseSections parses the HTML of each section and builds its document
+// model, rejecting documents over the node and depth limits. A section's own
+// page setup takes precedence over the one in its HTML, and classes adds
+// formatting by class name, see Options.ClassStyles.
+func parseSections(ctx context.Context, sections []Section, limits Limits, classes map[string]Style) ([]*Document, error) {
+	g := guard{limits: limits}
+	g.start(ctx)
+	docs := make([]*Document, len(sections))
+	for i, section := range sections {
+		root, err := html.Parse(strings.NewReader(UnescapeUnicodeHTML(section.HTML)))
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse HTML index %d: %w", i, err)
+		}
+		if err := g.check(root); err != nil {
+			return nil, err
+		}
+		docs[i] = BuildDocument(root)
+		page := section.Page.Merge(docs[i].Page)
+		if page.PageNumberStart == 0 {
+			page.PageNumberStart = docs[i].Page.PageNumberStart
+		}
+		docs[i].Page = page
+		if len(classes) > 0 {
+			docs[i].applyClassStyles(classes)
+		}
+	}
+	return docs, nil
+}
+
+// applyClassStyles merges the style of each class of an element, in the
+// order of its class attribute, over the element's own style.
+func (d *Document) applyClassStyles(classes map[string]Style) {
+	var apply func(e *Element)
+	apply = func(e *Element) {
+		if e.Source != nil && e.Source.Type == html.ElementNode && e.Kind != KindText {
+			for _, class := range strings.Fields(GetAttrValue(e.Source.Attr, "class")) {
+				if s, ok := classes[class]; ok {
+					e.Style = e.Style.merge(s)
+				}
+			}
+		}
+		for _, ch := range e.Children {
+			apply(ch)
+		}
+	}
+	apply(d.Body)
+	for _, regions := range []map[string][]*Element{d.Headers, d.Footers} {
+		for _, elements := range regions {
+			for _, e := range elements {
+				apply(e)
+			}
+		}
+	}
+}
+
+// isPageRegion reports whether a header or footer region is a direct child
+// of <body>, heading or closing the page rather than a part of the content.
+func isPageRegion(e *Element) bool {
+	p := e.Source.Parent
+	return p == nil || p.Type == html.ElementNode && p.Data == "body"
+}
+
+// documentLang returns the lang attribute of a document's <html> element.
+func documentLang(root *html.Node) string {
+	for n := root.FirstChild; n != nil; n = n.NextSibling {
+		if n.Type == html.ElementNode && n.Data == "html" {
+			return strings.TrimSpace(GetAttrValue(n.Attr, "lang"))
+		}
+	}
+	return ""
+}
+
+// containerElements are the elements built as KindContainer.
+var containerElements = map[string]bool{
+	"html": true, "body": true, "div": true, "section": true, "article": true,
+	"nav": true, "main": true, "aside": true, "figure": true, "figcaption": true,
+	"form": true, "fieldset": true, "address": true, "details": true,
+	"summary": true, "dl": true, "dt": true, "dd": true, "center": true,
+}
+
+// skippedElements hold no content of the document body.
+var skippedElements = map[string]bool{
+	"head": true, "title": true, "style": true, "script": true, "meta": true,
+	"link": true, "template": true, "noscript": true,
+}
+
+// fontSizes maps the size attribute of <font> to points.
+var fontSizes = map[string]float64{"1": 8, "2": 10, "3": 12, "4": 14, "5": 18, "6": 24, "7": 36}
+
+// modelBuilder builds the document model of one HTML document.
+type modelBuilder struct {
+	doc *Document
+}
+
+// children builds the children of n. In block context consecutive inline
+// children are wrapped in implicit paragraphs, and whitespace between blocks
+// is dropped.
+func (b *modelBuilder) children(n *html.Node, block bool) []*Element {
+	var out []*Element
+	for ch := n.FirstChild; ch != nil; ch = ch.NextSibling {
+		if e := b.element(ch, block); e != nil {
+			out = append(out, e)
+		}
+	}
+	if !block {
+		return out
+	}
+
+	var grouped, run []*Element
+	flush := func() {
+		for _, e := range run {
+			if e.Kind != KindText || strings.TrimSpace(e.Text) != "" {
+				grouped = append(grouped, &Element{Kind: KindParagraph, Implicit: true, Children: run})
+				break
+			}
+		}
+		run = nil
+	}
+	for _, e := range out {
+		if e.IsBlock() {
+			flush()
+			grouped = append(grouped, e)
+		} else {
+			run = append(run, e)
+		}
+	}
+	flush()
+	return grouped
+}
+
+// element builds the element for n, or returns nil for nodes that hold no
+// body content. block tells whether n sits in block context.
+func (b *modelBuilder) element(n *html.Node, block bool) *Element {
+	switch n.Type {
+	case html.TextNode:
+		return &Element{Kind: KindText, Text: n.Data, Source: n}
+	case html.ElementNode:
+	default:
+		return nil
+	}
+
+	e := &Element{Type: EffectiveNodeType(n), Source: n}
+	if skippedElements[e.Type] {
+		return nil
+	}
+	attrs := GetAttrMap(n.Attr)
+	switch align := strings.ToLower(attrs["align"]); align {
+	case "left", "center", "right":
+		e.Align = align
+	}
+	if dir := strings.ToLower(attrs["dir"]); dir == "rtl" || dir == "ltr" {
+		e.Dir = dir
+	}
+	e.Lang = strings.TrimSpace(attrs["lang"])
+	e.Style.FontFamily = StyleProperty(n, "font-family")
+
+	if field := PageField(n); field != "" {
+		e.Kind, e.Field = KindField, field
+		return e
+	}
+	if reason, ok := unsupportedElements[e.Type]; ok {
+		e.Kind, e.Reason = KindUnsupported, reason
+		return e
+	}
+
+	switch e.Type {
+	case "header", "footer":
+		e.Kind = KindContainer
+		e.Variant = PageVariant(n)
+		e.Children = b.children(n, true)
+		if e.Type == "header" {
+			b.doc.Headers[e.Variant] = append(b.doc.Headers[e.Variant], e)
+		} else {
+			b.doc.Footers[e.Variant] = append(b.doc.Footers[e.Variant], e)
+		}
+		if isPageRegion(e) {
+			return nil
+		}
+		return &Element{Kind: KindRegion, Children: []*Element{e}}
+	case "p":
+		e.Kind = KindParagraph
+	case "h1", "h2", "h3", "h4", "h5", "h6":
+		e.Kind = KindHeading
+		e.Level = int(e.Type[1] - '0')
+	case "br":
+		e.Kind = KindBreak
+		return e
+	case "hr":
+		e.Kind = KindRule
+		e.RuleSize, _ = strconv.Atoi(attrs["size"])
+		e.Style.Color = attrs["color"]
+		return e
+	case "img":
+		e.Kind = KindImage
+		e.Src, e.Alt = attrs["src"], attrs["alt"]
+		e.ImageWidth, _ = strconv.ParseFloat(strings.TrimSuffix(attrs["width"], "px"), 64)
+		e.ImageHeight, _ = strconv.ParseFloat(strings.TrimSuffix(attrs["height"], "px"), 64)
+		return e
+	case "ul", "ol":
+		e.Kind = KindList
+		e.Ordered = e.Type == "ol"
+		for li := n.FirstChild; li != nil; li = li.NextSibling {
+			if li.Type == html.ElementNode && EffectiveNodeType(li) == "li" {
+				if item := b.element(li, false); item != nil {
+					item.Kind = KindListItem
+					e.Children = append(e.Children, item)
+				}
+			}
+		}
+		return e
+	case "table":
+		e.Kind = KindTable
+		e.Border = attrs["border"] != "" && attrs["border"] != "0"
+		e.Width = attrs["width"]
+		b.tableRows(e, n)
+		return e
+	case "pre":
+		e.Kind = KindPre
+		e.Style.Code = true
+	case "blockquote":
+		e.Kind = KindQuote
+		e.Children = b.children(n, block)
+		return e
+	case "a":
+		// An anchor without a target, such as <a name>, is not a link.
+		e.Kind = KindSpan
+		if href := strings.TrimSpace(attrs["href"]); href != "" {
+			e.Kind = KindLink
+			e.Href = href
+			e.Style.Underline = true
+			e.Style.Color = "#0000FF"
+		}
+	case "b", "strong":
+		e.Kind = KindSpan
+		e.Style.Bold = true
+	case "i", "em":
+		e.Kind = KindSpan
+		e.Style.Italic = true
+	case "u":
+		e.Kind = KindSpan
+		e.Style.Underline = true
+	case "code":
+		e.Kind = KindSpan
+		e.Style.Code = true
+	case "font":
+		e.Kind = KindSpan
+		e.Style.Color = attrs["color"]
+		e.Style.FontSize = fontSizes[attrs["size"]]
+		if e.Style.FontFamily == "" {
+			e.Style.FontFamily = attrs["face"]
+		}
+	default:
+		if containerElements[e.Type] {
+			e.Kind = KindContainer
+			if e.Type == "center" && e.Align == "" {
+				e.Align = "center"
+			}
+			e.Children = b.children(n, block)
+			return e
+		}
+		e.Kind = KindSpan
+	}
+
+	e.Children = b.children(n, false)
+	if e.Kind == KindSpan && block {
+		// Inline wrappers around blocks, such as <span><p>..</p></span>, act
+		// as containers so the content around the blocks still forms
+		// paragraphs.
+		for _, ch := range e.Children {
+			if ch.IsBlock() {
+				e.Kind = KindContainer
+				e.Children = b.children(n, true)
+				break
+			}
+		}
+	}
+	return e
+}
+
+// tableRows adds the rows of a table to t, looking through row groups and
+// other wrappers between the table, its rows and their cells.
+func (b *modelBuilder) tableRows(t *Element, n *html.Node) {
+	for ch := n.FirstChild; ch != nil; ch = ch.NextSibling {
+		if ch.Type != html.ElementNode {
+			continue
+		}
+		if EffectiveNodeType(ch) != "tr" {
+			b.tableRows(t, ch)
+			continue
+		}
+		row := &Element{Kind: KindTableRow, Type: "tr", Source: ch}
+		b.tableCells(row, ch, GetAttrValue(ch.Attr, "bgcolor"))
+		if len(row.Children) > 0 {
+			t.Children = append(t.Children, row)
+		}
+	}
+}
+
+// tableCells adds the <td> and <th> cells of a row, including those wrapped
+// in <div> or <span> elements.
+func (b *modelBuilder) tableCells(row *Element, n *html.Node, bgcolor string) {
+	for ch := n.FirstChild; ch != nil; ch = ch.NextSibling {
+		if ch.Type != html.ElementNode {
+			continue
+		}
+		switch EffectiveNodeType(ch) {
+		case "td", "th":
+			cell := b.element(ch, false)
+			cell.Kind = KindTableCell
+			cell.Header = cell.Type == "th"
+			cell.Style.Bold = cell.Header
+			cell.Background = GetAttrValue(ch.Attr, "bgcolor")
+			if cell.Background == "" {
+				cell.Background = bgcolor
+			}
+			row.Header = row.Header || cell.Header
+			row.Children = append(row.Children, cell)
+		case "div", "span":
+			b.tableCells(row, ch, bgcolor)
+		}
+	}
+}
@@ -0,0 +1,208 @@
+package converter
+
+import (
+	"strings"
+	"testing"
+
+	"golang.org/x/net/html"
+)
+
+func buildTestDocument(t *testing.T, content string) *Document {
+	t.Helper()
+	root, err := html.Parse(strings.NewReader(content))
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	return BuildDocument(root)
+}
+
+// blockKinds returns the kinds of the body's blocks, looking through containers.
+func blockKinds(e *Element) []Kind {
+	var kinds []Kind
+	for _, ch := range e.Children {
+		if ch.Kind == KindContainer {
+			kinds = append(kinds, blockKinds(ch)...)
+		} else {
+			kinds = append(kinds, ch.Kind)
+		}
+	}
+	return kinds
+}
+
+func TestBuildDocument(t *testing.T) {
+	doc := buildTestDocument(t, `<html lang="de"><head><title>Report</title><style>p{}</style></head><body>
+		<header data-page="first"><b>Report</b> header</header>
+		<h2>Title</h2>
+		Loose <b>text</b>
+		<section><p>Para</p></section>
+		<ul><li>One</li><li>Two<ol><li>Nested</li></ol></li></ul>
+		<table border="1"><tbody><tr bgcolor="#EEEEEE"><th>H</th><div><td bgcolor="#FF0000">a</td></div></tr></tbody></table>
+		<footer>Page <span data-field="page"></span></footer>
+	</body></html>`)
+
+	if doc.Lang != "de" || doc.Metadata.Title != "Report" {
+		t.Errorf("expected lang de and title Report, got %q and %q", doc.Lang, doc.Metadata.Title)
+	}
+	if len(doc.Headers["first"]) != 1 || len(doc.Footers["default"]) != 1 {
+		t.Fatalf("expected a first-page header and a default footer, got %v and %v", doc.Headers, doc.Footers)
+	}
+	if got := strings.TrimSpace(doc.Headers["first"][0].TextContent()); got != "Report header" {
+		t.Errorf("header text = %q", got)
+	}
+
+	want := []Kind{KindHeading, KindParagraph, KindParagraph, KindList, KindTable}
+	got := blockKinds(doc.Body)
+	if len(got) != len(want) {
+		t.Fatalf("blocks = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("blocks = %v, want %v", got, want)
+		}
+	}
+
+	var body []*Element
+	var collect func(*Element)
+	collect = func(e *Element) {
+		for _, ch := range e.Children {
+			if ch.Kind == KindContainer {
+				collect(ch)
+			} else {
+				body = append(body, ch)
+			}
+		}
+	}
+	collect(doc.Body)
+
+	if h := body[0]; h.Level != 2 || h.TextContent() != "Title" {
+		t.Errorf("heading = level %d %q", h.Level, h.TextContent())
+	}
+	if p := body[1]; !p.Implicit || strings.TrimSpace(p.TextContent()) != "Loose text" {
+		t.Errorf("expected an implicit paragraph holding the loose text, got %+v", p)
+	}
+	if p := body[2]; p.Implicit {
+		t.Error("expected <p> to build an explicit paragraph")
+	}
+
+	list := body[3]
+	if len(list.Children) != 2 || list.Ordered {
+		t.Fatalf("expected an unordered list of two items, got %+v", list)
+	}
+	nested := list.Children[1].Children[1]
+	if nested.Kind != KindList || !nested.Ordered || nested.TextContent() != "Nested" {
+		t.Errorf("expected a nested ordered list, got %+v", nested)
+	}
+
+	table := body[4]
+	if !table.Border || len(table.Children) != 1 {
+		t.Fatalf("expected a bordered table with one row, got %+v", table)
+	}
+	row := table.Children[0]
+	if !row.Header || len(row.Children) != 2 {
+		t.Fatalf("expected a header row with two cells, got %+v", row)
+	}
+	if th := row.Children[0]; !th.Header || !th.Style.Bold || th.Background != "#EEEEEE" {
+		t.Errorf("expected a bold header cell with the row's background, got %+v", th)
+	}
+	if td := row.Children[1]; td.Header || td.Background != "#FF0000" {
+		t.Errorf("expected a data cell with its own background, got %+v", td)
+	}
+}
+
+func TestBuildDocumentInline(t *testing.T) {
+	doc := buildTestDocument(t, `<p><font size="5" color="#FF0000" face="Times">Big</font>
+		<a href="https://example.com">link</a> <code>x</code><iframe src="x"></iframe><span data-field="pages"></span></p>`)
+
+	p := doc.Body.Children[0].Children[0].Children[0]
+	if p.Kind != KindParagraph {
+		t.Fatalf("expected a paragraph, got %v", p.Kind)
+	}
+	var kinds []Kind
+	for _, ch := range p.Children {
+		if ch.Kind != KindText {
+			kinds = append(kinds, ch.Kind)
+		}
+	}
+	want := []Kind{KindSpan, KindLink, KindSpan, KindUnsupported, KindField}
+	if len(kinds) != len(want) {
+		t.Fatalf("inline kinds = %v, want %v", kinds, want)
+	}
+	for i := range want {
+		if kinds[i] != want[i] {
+			t.Fatalf("inline kinds = %v, want %v", kinds, want)
+		}
+	}
+
+	font := p.Children[0]
+	if font.Style != (Style{Color: "#FF0000", FontFamily: "Times", FontSize: 18}) {
+		t.Errorf("font style = %+v", font.Style)
+	}
+	link := p.Children[2]
+	if link.Href != "https://example.com" || !link.Style.Underline {
+		t.Errorf("link = %+v", link)
+	}
+	if code := p.Children[4]; !code.Style.Code {
+		t.Errorf("expected <code> to be monospaced, got %+v", code.Style)
+	}
+	if field := p.Children[6]; field.Field != "pages" {
+		t.Errorf("expected a pages field, got %+v", field)
+	}
+}
+
+func TestBuildDocumentAnchor(t *testing.T) {
+	doc := buildTestDocument(t, `<p><a name="top">Top</a></p>`)
+
+	a := doc.Body.Children[0].Children[0].Children[0].Children[0]
+	if a.Kind != KindSpan || a.Style != (Style{}) {
+		t.Errorf("expected an anchor without href to be a plain span, got %+v", a)
+	}
+}
+
+func TestStyleMerge(t *testing.T) {
+	s := Style{Bold: true, Color: "#000000", FontSize: 12}.merge(Style{Italic: true, Color: "#FF0000"})
+	want := Style{Bold: true, Italic: true, Color: "#FF0000", FontSize: 12}
+	if s != want {
+		t.Errorf("merge = %+v, want %+v", s, want)
+	}
+}
+
+func TestApplyClassStyles(t *testing.T) {
+	doc := buildTestDocument(t, `<header class="muted">Head</header>
+		<p class="note big">A <a href="#x" class="plain">link</a></p><p>B</p>`)
+	doc.applyClassStyles(map[string]Style{
+		"note":  {Italic: true, Color: "#555555"},
+		"big":   {FontSize: 14, Color: "#000000"},
+		"plain": {Color: "#333333"},
+		"muted": {Color: "#999999"},
+	})
+
+	var paras, links []*Element
+	var collect func(e *Element)
+	collect = func(e *Element) {
+		switch e.Kind {
+		case KindParagraph:
+			paras = append(paras, e)
+		case KindLink:
+			links = append(links, e)
+		}
+		for _, ch := range e.Children {
+			collect(ch)
+		}
+	}
+	collect(doc.Body)
+	if len(paras) != 2 || len(links) != 1 {
+		t.Fatalf("expected 2 paragraphs and a link, got %d and %d", len(paras), len(links))
+	}
+	if want := (Style{Italic: true, Color: "#000000", FontSize: 14}); paras[0].Style != want {
+		t.Errorf("expected the classes merged in order, got %+v", paras[0].Style)
+	}
+	if links[0].Style.Color != "#333333" || !links[0].Style.Underline {
+		t.Errorf("expected the class color over the link's, got %+v", links[0].Style)
+	}
+	if paras[1].Style != (Style{}) {
+		t.Errorf("expected no style without a class, got %+v", paras[1].Style)
+	}
+	if head := doc.Headers["default"][0]; head.Style.Color != "#999999" {
+		t.Errorf("expected classes applied in headers, got %+v", head.Style)
+	}
+}
@@ -0,0 +1,36 @@
+package converter
+
+// Options configures a converter. Zero fields keep the converter's defaults.
+// Page setup given in the HTML itself (<meta> tags or a CSS @page rule) takes
+// precedence over Page, while Metadata takes precedence over the HTML's
+// <title> and <meta> tags. Page.PageNumberStart numbers the first section
+// only. Fields that do not apply to an output format are ignored.
+type Options struct {
+	Page          PageSetup
+	FontFamily    string  // default font family, e.g. "Arial" or "Times New Roman"
+	FontSize      float64 // default font size in points
+	KeepLangSpans bool    // Markdown: keep <span lang> elements as inline HTML
+	Metadata      Metadata
+	Limits        Limits
+	Strict        bool // fail on the first unsupported element instead of warning
+	NoLocalImages bool // PDF: load images from data: URIs and ResourceDirs only, never elsewhere on the filesystem
+
+	// ClassStyles adds formatting to the elements with a class, keyed by
+	// class name. Markdown marks it on inline elements only.
+	ClassStyles map[string]Style
+	// Fonts lists TrueType families to embed, keyed by family name, as if
+	// registered with RegisterFont. PDF only.
+	Fonts map[string]FontFiles
+	// ResourceDirs are the directories relative image sources are looked up
+	// in, in order, before the working directory. PDF only.
+	ResourceDirs []string
+}
+
+// firstOptions returns the first of the options passed to a constructor, or
+// the zero Options when there are none.
+func firstOptions(opts []Options) Options {
+	if len(opts) == 0 {
+		return Options{}
+	}
+	return opts[0]
+}
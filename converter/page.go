@@ -0,0 +1,279 @@
+package converter
+
+import (
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// PageSize is a paper size in millimeters, given in portrait orientation.
+type PageSize struct {
+	Width  float64
+	Height float64
+}
+
+// Common paper sizes.
+var (
+	PageA3     = PageSize{Width: 297, Height: 420}
+	PageA4     = PageSize{Width: 210, Height: 297}
+	PageA5     = PageSize{Width: 148, Height: 210}
+	PageLetter = PageSize{Width: 215.9, Height: 279.4}
+	PageLegal  = PageSize{Width: 215.9, Height: 355.6}
+)
+
+// Orientation is the direction a page is printed in.
+type Orientation string
+
+// Page orientations.
+const (
+	Portrait  Orientation = "portrait"
+	Landscape Orientation = "landscape"
+)
+
+// Margins are page margins in millimeters.
+type Margins struct {
+	Top    float64
+	Right  float64
+	Bottom float64
+	Left   float64
+}
+
+// PageSetup describes the page geometry of a document or of one section.
+// Zero fields are inherited from the previous section, or from the
+// converter's defaults for the first one.
+type PageSetup struct {
+	Size            PageSize
+	Orientation     Orientation
+	Margins         *Margins
+	HeaderDistance  float64 // from the top edge of the page to the header, in mm
+	FooterDistance  float64 // from the bottom edge of the page to the footer, in mm
+	PageNumberStart int     // restarts page numbering at this value when > 0
+}
+
+// Section is an HTML input converted as its own section. Fields set in Page
+// take precedence over the page setup given by <meta> tags in the HTML.
+type Section struct {
+	HTML string
+	Page PageSetup
+}
+
+// HTMLSections returns one section per HTML input, each with the page setup
+// given in its own HTML.
+func HTMLSections(htmlContents []string) []Section {
+	sections := make([]Section, len(htmlContents))
+	for i, content := range htmlContents {
+		sections[i] = Section{HTML: content}
+	}
+	return sections
+}
+
+// Merge returns p with its unset fields taken from base. PageNumberStart
+// applies to a single section and is never inherited.
+func (p PageSetup) Merge(base PageSetup) PageSetup {
+	if p.Size == (PageSize{}) {
+		p.Size = base.Size
+	}
+	if p.Orientation == "" {
+		p.Orientation = base.Orientation
+	}
+	if p.Margins == nil {
+		p.Margins = base.Margins
+	}
+	if p.HeaderDistance == 0 {
+		p.HeaderDistance = base.HeaderDistance
+	}
+	if p.FooterDistance == 0 {
+		p.FooterDistance = base.FooterDistance
+	}
+	return p
+}
+
+// ParsePageSetup reads the page setup of an HTML document from <meta> tags:
+//
+//	<meta name="page-size" content="A4 landscape">     (A3, A4, A5, Letter, Legal or "210mm 297mm")
+//	<meta name="page-orientation" content="landscape">
+//	<meta name="page-margin" content="20mm 15mm">      (1 to 4 values, as in CSS)
+//	<meta name="page-header-distance" content="10mm">
+//	<meta name="page-footer-distance" content="10mm">
+//	<meta name="page-number-start" content="1">
+//
+// and from the size and margin properties of CSS @page rules in <style>
+// elements. Later declarations win.
+func ParsePageSetup(root *html.Node) PageSetup {
+	var setup PageSetup
+	var walk func(*html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.ElementNode && n.Data == "style" {
+			parsePageRules(ExtractText(n), &setup)
+		}
+		if n.Type == html.ElementNode && n.Data == "meta" {
+			attrs := GetAttrMap(n.Attr)
+			content := strings.TrimSpace(attrs["content"])
+			switch strings.ToLower(attrs["name"]) {
+			case "page-size":
+				setup.Size, setup.Orientation = parsePageSize(content, setup.Orientation)
+			case "page-orientation":
+				if o := Orientation(strings.ToLower(content)); o == Portrait || o == Landscape {
+					setup.Orientation = o
+				}
+			case "page-margin":
+				if m, ok := ParseMargins(content); ok {
+					setup.Margins = &m
+				}
+			case "page-header-distance":
+				setup.HeaderDistance, _ = parseLengthMM(content)
+			case "page-footer-distance":
+				setup.FooterDistance, _ = parseLengthMM(content)
+			case "page-number-start":
+				if start, err := strconv.Atoi(content); err == nil && start > 0 {
+					setup.PageNumberStart = start
+				}
+			}
+		}
+		if n.Type == html.ElementNode && n.Data == "body" {
+			return
+		}
+		for ch := n.FirstChild; ch != nil; ch = ch.NextSibling {
+			walk(ch)
+		}
+	}
+	walk(root)
+	return setup
+}
+
+var (
+	cssCommentRe  = regexp.MustCompile(`(?s)/\*.*?\*/`)
+	cssPageRuleRe = regexp.MustCompile(`(?i)@page\s*([^{]*)\{([^}]*)\}`)
+)
+
+// parsePageRules applies the size and margin properties of CSS @page rules to
+// setup. Rules with a page selector such as :first are ignored.
+func parsePageRules(css string, setup *PageSetup) {
+	css = cssCommentRe.ReplaceAllString(css, "")
+	for _, m := range cssPageRuleRe.FindAllStringSubmatch(css, -1) {
+		if strings.TrimSpace(m[1]) != "" {
+			continue
+		}
+		for _, decl := range strings.Split(m[2], ";") {
+			name, value, ok := strings.Cut(decl, ":")
+			if !ok {
+				continue
+			}
+			value = strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(value), "!important"))
+			switch strings.ToLower(strings.TrimSpace(name)) {
+			case "size":
+				size, orientation := parsePageSize(value, setup.Orientation)
+				if size != (PageSize{}) {
+					setup.Size = size
+				}
+				setup.Orientation = orientation
+			case "margin":
+				if margins, ok := ParseMargins(value); ok {
+					setup.Margins = &margins
+				}
+			}
+		}
+	}
+}
+
+// ParsePageSize parses a page size given like the CSS @page size property,
+// such as "A4", "letter landscape" or "210mm 297mm". ok is false if s holds
+// neither a size nor an orientation.
+func ParsePageSize(s string) (size PageSize, orientation Orientation, ok bool) {
+	size, orientation = parsePageSize(s, "")
+	return size, orientation, size != (PageSize{}) || orientation != ""
+}
+
+// parsePageSize parses a CSS-like page size such as "A4", "letter landscape"
+// or "210mm 297mm". Unrecognized values leave the size unset.
+func parsePageSize(s string, orientation Orientation) (PageSize, Orientation) {
+	var size PageSize
+	var lengths []float64
+	for _, field := range strings.Fields(strings.ToLower(s)) {
+		switch field {
+		case "a3":
+			size = PageA3
+		case "a4":
+			size = PageA4
+		case "a5":
+			size = PageA5
+		case "letter":
+			size = PageLetter
+		case "legal":
+			size = PageLegal
+		case "portrait":
+			orientation = Portrait
+		case "landscape":
+			orientation = Landscape
+		default:
+			if v, ok := parseLengthMM(field); ok {
+				lengths = append(lengths, v)
+			}
+		}
+	}
+	switch len(lengths) {
+	case 1:
+		size = PageSize{Width: lengths[0], Height: lengths[0]}
+	case 2:
+		size = PageSize{Width: lengths[0], Height: lengths[1]}
+		if size.Width > size.Height {
+			size = PageSize{Width: lengths[1], Height: lengths[0]}
+			if orientation == "" {
+				orientation = Landscape
+			}
+		}
+	}
+	return size, orientation
+}
+
+// ParseMargins parses a CSS margin shorthand of one to four lengths, such as
+// "20mm" or "1in 0.75in". Numbers without a unit are taken to be millimeters.
+func ParseMargins(s string) (Margins, bool) {
+	var v []float64
+	for _, field := range strings.Fields(s) {
+		mm, ok := parseLengthMM(field)
+		if !ok {
+			return Margins{}, false
+		}
+		v = append(v, mm)
+	}
+	switch len(v) {
+	case 1:
+		return Margins{Top: v[0], Right: v[0], Bottom: v[0], Left: v[0]}, true
+	case 2:
+		return Margins{Top: v[0], Right: v[1], Bottom: v[0], Left: v[1]}, true
+	case 3:
+		return Margins{Top: v[0], Right: v[1], Bottom: v[2], Left: v[1]}, true
+	case 4:
+		return Margins{Top: v[0], Right: v[1], Bottom: v[2], Left: v[3]}, true
+	}
+	return Margins{}, false
+}
+
+// parseLengthMM converts a CSS length (mm, cm, in, pt, pc or px) to
+// millimeters. Numbers without a unit are taken to be millimeters.
+func parseLengthMM(s string) (float64, bool) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	units := []struct {
+		suffix string
+		mm     float64
+	}{
+		{"mm", 1}, {"cm", 10}, {"in", 25.4}, {"pt", 25.4 / 72}, {"pc", 25.4 / 6}, {"px", 25.4 / 96},
+	}
+	factor := 1.0
+	for _, u := range units {
+		if strings.HasSuffix(s, u.suffix) {
+			s, factor = strings.TrimSuffix(s, u.suffix), u.mm
+			break
+		}
+	}
+	v, err := strconv.ParseFloat(s, 64)
+	v *= factor
+	if err != nil || v < 0 || math.IsInf(v, 0) || math.IsNaN(v) {
+		return 0, false
+	}
+	return v, true
+}
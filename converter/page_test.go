@@ -0,0 +1,130 @@
+package converter
+
+import (
+	"math"
+	"strings"
+	"testing"
+
+	"golang.org/x/net/html"
+)
+
+func TestParsePageSetup(t *testing.T) {
+	doc, _ := html.Parse(strings.NewReader(`<html><head>
+		<meta name="page-size" content="A5 landscape">
+		<meta name="page-margin" content="1cm 20mm">
+		<meta name="page-footer-distance" content="0.5in">
+		<meta name="page-number-start" content="3">
+	</head><body><meta name="page-size" content="A3"></body></html>`))
+
+	got := ParsePageSetup(doc)
+	if got.Size != PageA5 || got.Orientation != Landscape {
+		t.Errorf("expected landscape A5, got %+v %q", got.Size, got.Orientation)
+	}
+	if got.Margins == nil || *got.Margins != (Margins{Top: 10, Right: 20, Bottom: 10, Left: 20}) {
+		t.Errorf("unexpected margins %+v", got.Margins)
+	}
+	if math.Abs(got.FooterDistance-12.7) > 1e-9 {
+		t.Errorf("FooterDistance = %v, want 12.7", got.FooterDistance)
+	}
+	if got.PageNumberStart != 3 {
+		t.Errorf("PageNumberStart = %d, want 3", got.PageNumberStart)
+	}
+}
+
+func TestParsePageSize(t *testing.T) {
+	tests := []struct {
+		input       string
+		size        PageSize
+		orientation Orientation
+	}{
+		{"letter", PageLetter, ""},
+		{"legal portrait", PageLegal, Portrait},
+		{"100mm 150mm", PageSize{Width: 100, Height: 150}, ""},
+		{"150mm 100mm", PageSize{Width: 100, Height: 150}, Landscape},
+		{"unknown", PageSize{}, ""},
+		{"inf nan", PageSize{}, ""},
+	}
+
+	for _, tc := range tests {
+		size, orientation := parsePageSize(tc.input, "")
+		if size != tc.size || orientation != tc.orientation {
+			t.Errorf("parsePageSize(%q) = %+v %q, want %+v %q", tc.input, size, orientation, tc.size, tc.orientation)
+		}
+	}
+}
+
+func TestPageSetupMerge(t *testing.T) {
+	base := PageSetup{Size: PageA4, Orientation: Portrait, Margins: &Margins{Top: 15}, FooterDistance: 10, PageNumberStart: 5}
+	got := PageSetup{Orientation: Landscape}.Merge(base)
+
+	if got.Size != PageA4 || got.Orientation != Landscape || got.Margins != base.Margins || got.FooterDistance != 10 {
+		t.Errorf("unexpected merge result %+v", got)
+	}
+	if got.PageNumberStart != 0 {
+		t.Errorf("PageNumberStart should not be inherited, got %d", got.PageNumberStart)
+	}
+}
+
+func TestParsePageSetupCSS(t *testing.T) {
+	doc, _ := html.Parse(strings.NewReader(`<html><head><style>
+		body { margin: 0; }
+		@page :first { size: A3; }
+		@page {
+			size: letter landscape; /* wide */
+			margin: 20mm 15mm 25mm;
+		}
+	</style></head><body></body></html>`))
+
+	got := ParsePageSetup(doc)
+	if got.Size != PageLetter || got.Orientation != Landscape {
+		t.Errorf("expected landscape Letter, got %+v %q", got.Size, got.Orientation)
+	}
+	if got.Margins == nil || *got.Margins != (Margins{Top: 20, Right: 15, Bottom: 25, Left: 15}) {
+		t.Errorf("unexpected margins %+v", got.Margins)
+	}
+}
+
+func TestParsePageSizeAndMargins(t *testing.T) {
+	if size, orientation, ok := ParsePageSize("A5 landscape"); !ok || size != PageA5 || orientation != Landscape {
+		t.Errorf("ParsePageSize(A5 landscape) = %+v %q %v", size, orientation, ok)
+	}
+	if _, _, ok := ParsePageSize("huge"); ok {
+		t.Error("expected an unknown page size to fail")
+	}
+	if m, ok := ParseMargins("1in 10"); !ok || m != (Margins{Top: 25.4, Right: 10, Bottom: 25.4, Left: 10}) {
+		t.Errorf("ParseMargins(1in 10) = %+v %v", m, ok)
+	}
+	for _, margins := range []string{"wide", "nan", "10mm inf", "-Inf"} {
+		if _, ok := ParseMargins(margins); ok {
+			t.Errorf("expected margins %q to fail", margins)
+		}
+	}
+	if _, _, ok := ParsePageSize("inf nan"); ok {
+		t.Error("expected a page size that is not finite to fail")
+	}
+}
+
+func TestParseLengthMM(t *testing.T) {
+	tests := []struct {
+		input string
+		mm    float64
+		ok    bool
+	}{
+		{"10", 10, true},
+		{"2cm", 20, true},
+		{"1in", 25.4, true},
+		{"-1mm", 0, false},
+		{"inf", 0, false},
+		{"+Inf", 0, false},
+		{"NaN", 0, false},
+		{"1e400", 0, false},
+		{"1e308in", 0, false},
+	}
+
+	for _, tc := range tests {
+		mm, ok := parseLengthMM(tc.input)
+		if mm != tc.mm || ok != tc.ok {
+			t.Errorf("parseLengthMM(%q) = %v %v, want %v %v", tc.input, mm, ok, tc.mm, tc.ok)
+		}
+	}
+}
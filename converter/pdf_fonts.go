@@ -0,0 +1,220 @@
+package converter
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"unicode"
+
+	"golang.org/x/image/font/sfnt"
+)
+
+// FontFiles lists the TrueType files of one font family. Regular is required;
+// styles without a file fall back to the closest registered one.
+type FontFiles struct {
+	Regular    string
+	Bold       string
+	Italic     string
+	BoldItalic string
+}
+
+// pdfFont is an embedded TrueType family, keyed by gofpdf style ("", "B", "I", "BI").
+type pdfFont struct {
+	styles map[string][]byte
+	face   *sfnt.Font // regular style, used to look up glyph coverage
+	buf    sfnt.Buffer
+}
+
+// fontRun is a piece of text set in a single font family.
+type fontRun struct {
+	family string
+	text   string
+}
+
+// pdfCoreRunes are the characters outside Latin-1 that the cp1252 encoding of
+// the PDF core fonts covers.
+const pdfCoreRunes = "€‚ƒ„…†‡ˆ‰Š‹ŒŽ‘’“”•–—˜™š›œžŸ"
+
+// RegisterFont embeds a TrueType font family in the PDF. Text set in it is
+// written as UTF-8, so every script the font covers renders correctly.
+// <font face> and CSS font-family refer to the family by name, and a family
+// named in Options.FontFamily becomes the default font once registered.
+func (c *HTMLToPDFConverter) RegisterFont(family string, files FontFiles) error {
+	if files.Regular == "" {
+		return fmt.Errorf("failed to register font %q: no regular style file", family)
+	}
+	key := strings.ToLower(strings.TrimSpace(family))
+	font := &pdfFont{styles: map[string][]byte{}}
+	for _, f := range []struct{ style, path string }{
+		{"", files.Regular}, {"B", files.Bold}, {"I", files.Italic}, {"BI", files.BoldItalic},
+	} {
+		if f.path == "" {
+			continue
+		}
+		data, err := os.ReadFile(f.path)
+		if err != nil {
+			return fmt.Errorf("failed to read font %q: %w", f.path, err)
+		}
+		c.pdf.AddUTF8FontFromBytes(key, f.style, data)
+		if err := c.pdf.Error(); err != nil {
+			c.pdf.ClearError()
+			return fmt.Errorf("failed to load font %q: %w", f.path, err)
+		}
+		font.styles[f.style] = data
+	}
+	face, err := sfnt.Parse(font.styles[""])
+	if err != nil {
+		return fmt.Errorf("failed to load font %q: %w", files.Regular, err)
+	}
+	font.face = face
+	c.fonts[key] = font
+
+	oldBase := c.baseFamily
+	c.resolveBaseFont()
+	if c.fontFamily == oldBase {
+		c.fontFamily = c.baseFamily
+	}
+	return nil
+}
+
+// SetFontFallbacks sets the registered families tried, in order, for
+// characters the current font has no glyph for. This lets a paragraph mix
+// scripts that no single font covers, e.g. Latin, Arabic and CJK.
+func (c *HTMLToPDFConverter) SetFontFallbacks(families ...string) error {
+	fallbacks := make([]string, 0, len(families))
+	for _, family := range families {
+		key := strings.ToLower(strings.TrimSpace(family))
+		if _, ok := c.fonts[key]; !ok {
+			return fmt.Errorf("failed to set font fallback: font %q is not registered", family)
+		}
+		fallbacks = append(fallbacks, key)
+	}
+	c.fallbacks = fallbacks
+	return nil
+}
+
+// fontRuns splits text into runs by the first font, starting with the current
+// one and then the fallbacks, that has a glyph for each character. Spaces,
+// punctuation the run's font covers, and characters no font covers stay in
+// the run they appear in.
+func (c *HTMLToPDFConverter) fontRuns(text string) []fontRun {
+	if len(c.fallbacks) == 0 {
+		return []fontRun{{family: c.fontFamily, text: text}}
+	}
+	var runs []fontRun
+	start, family := 0, ""
+	for i, r := range text {
+		f := family
+		if !unicode.IsSpace(r) && (unicode.IsLetter(r) || !c.covers(family, r)) {
+			f = c.fontForRune(r, family)
+		}
+		if f == "" {
+			f = c.fontFamily
+		}
+		if f != family {
+			if i > start {
+				runs = append(runs, fontRun{family: family, text: text[start:i]})
+			}
+			start, family = i, f
+		}
+	}
+	return append(runs, fontRun{family: family, text: text[start:]})
+}
+
+// fontForRune returns the current family if it has a glyph for r, or else the
+// first fallback that does. Without any, it keeps the family of the run so far.
+func (c *HTMLToPDFConverter) fontForRune(r rune, family string) string {
+	if c.covers(c.fontFamily, r) {
+		return c.fontFamily
+	}
+	for _, fallback := range c.fallbacks {
+		if c.covers(fallback, r) {
+			return fallback
+		}
+	}
+	return family
+}
+
+// covers reports whether a font family has a glyph for r.
+func (c *HTMLToPDFConverter) covers(family string, r rune) bool {
+	font := c.fonts[strings.ToLower(family)]
+	if font == nil {
+		return r < 0x80 || (r >= 0xA0 && r <= 0xFF) || strings.ContainsRune(pdfCoreRunes, r)
+	}
+	index, err := font.face.GlyphIndex(&font.buf, r)
+	return err == nil && index != 0
+}
+
+// resolveBaseFont sets the default family from the one requested in
+// Options, falling back to Arial.
+func (c *HTMLToPDFConverter) resolveBaseFont() {
+	c.baseFamily = c.fontFor(c.baseName)
+	if c.baseFamily == "" {
+		c.baseFamily = "Arial"
+	}
+}
+
+// fontFor resolves a <font face> or CSS font-family list to the first family
+// that is either registered or a PDF core font. It returns "" if none is.
+func (c *HTMLToPDFConverter) fontFor(names string) string {
+	for _, name := range strings.Split(names, ",") {
+		name = strings.ToLower(strings.Trim(strings.TrimSpace(name), `"'`))
+		if _, ok := c.fonts[name]; ok {
+			return name
+		}
+		switch name {
+		case "arial", "helvetica", "sans-serif":
+			return "Arial"
+		case "times", "times new roman", "serif":
+			return "Times"
+		case "courier", "courier new", "monospace":
+			return "Courier"
+		}
+	}
+	return ""
+}
+
+// setFont selects a font, substituting the closest registered style when an
+// embedded family lacks the requested one.
+func (c *HTMLToPDFConverter) setFont(family, style string, size float64) {
+	font := c.fonts[strings.ToLower(family)]
+	c.utf8 = font != nil
+	if font != nil {
+		style = font.style(style)
+	}
+	c.pdf.SetFont(family, style, size)
+}
+
+// style returns the registered style closest to the requested one, keeping
+// underline, which gofpdf draws itself.
+func (f *pdfFont) style(style string) string {
+	underline := ""
+	if strings.Contains(style, "U") {
+		underline = "U"
+	}
+	bold, italic := strings.Contains(style, "B"), strings.Contains(style, "I")
+	var candidates []string
+	switch {
+	case bold && italic:
+		candidates = []string{"BI", "B", "I"}
+	case bold:
+		candidates = []string{"B"}
+	case italic:
+		candidates = []string{"I"}
+	}
+	for _, s := range candidates {
+		if _, ok := f.styles[s]; ok {
+			return s + underline
+		}
+	}
+	return underline
+}
+
+// encodeText prepares text for the current font: embedded fonts take UTF-8
+// as is, core fonts need it translated to cp1252.
+func (c *HTMLToPDFConverter) encodeText(text string) string {
+	if c.utf8 {
+		return text
+	}
+	return c.tr(text)
+}
@@ -0,0 +1,76 @@
+package converter
+
+import (
+	"fmt"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// Warning reports HTML content that a converter could not reproduce
+// faithfully and dropped.
+type Warning struct {
+	Element string // tag name, e.g. "iframe"
+	Path    string // element path from the document root, e.g. "html/body/div[2]/iframe"
+	Reason  string
+}
+
+func (w Warning) String() string {
+	return fmt.Sprintf("<%s> at %s: %s", w.Element, w.Path, w.Reason)
+}
+
+// WarningError is returned in strict mode (Options.Strict) for the first
+// warning of a conversion.
+type WarningError struct {
+	Warning Warning
+}
+
+func (e *WarningError) Error() string {
+	return "unsupported content: " + e.Warning.String()
+}
+
+// unsupportedElements lists the elements no converter renders, with the
+// reason reported in their warning. Their content is dropped.
+var unsupportedElements = map[string]string{
+	"iframe":   "embedded content is not supported",
+	"frame":    "embedded content is not supported",
+	"object":   "embedded content is not supported",
+	"embed":    "embedded content is not supported",
+	"video":    "audio and video are not supported",
+	"audio":    "audio and video are not supported",
+	"svg":      "SVG graphics are not supported",
+	"canvas":   "canvas drawings are not supported",
+	"math":     "MathML is not supported",
+	"input":    "form controls are not supported",
+	"select":   "form controls are not supported",
+	"textarea": "form controls are not supported",
+	"button":   "form controls are not supported",
+}
+
+// nodePath returns the path of an element from the document root, with the
+// position among same-named siblings where there are several.
+func nodePath(n *html.Node) string {
+	var parts []string
+	for ; n != nil && n.Type == html.ElementNode; n = n.Parent {
+		part := n.Data
+		index, count := 0, 0
+		if n.Parent != nil {
+			for s := n.Parent.FirstChild; s != nil; s = s.NextSibling {
+				if s.Type == html.ElementNode && s.Data == n.Data {
+					count++
+					if s == n {
+						index = count
+					}
+				}
+			}
+		}
+		if count > 1 {
+			part = fmt.Sprintf("%s[%d]", part, index)
+		}
+		parts = append(parts, part)
+	}
+	for i, j := 0, len(parts)-1; i < j; i, j = i+1, j-1 {
+		parts[i], parts[j] = parts[j], parts[i]
+	}
+	return strings.Join(parts, "/")
+}
@@ -0,0 +1,70 @@
+package converter
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+const warningsHTML = `<html><body>
+	<div><p>Intro</p></div>
+	<div><iframe src="https://example.com"></iframe><p>Kept</p></div>
+	<form><label>Name</label><input name="n"></form>
+</body></html>`
+
+func TestConverterWarnings(t *testing.T) {
+	for _, f := range Formats() {
+		conv := f.New()
+		if err := conv.ConvertSections(HTMLSections([]string{warningsHTML})); err != nil {
+			t.Fatalf("%s: ConvertSections failed: %v", f.Name, err)
+		}
+		warnings := conv.Warnings()
+		if len(warnings) != 2 {
+			t.Fatalf("%s: expected 2 warnings, got %v", f.Name, warnings)
+		}
+		if w := warnings[0]; w.Element != "iframe" || w.Path != "html/body/div[2]/iframe" || w.Reason == "" {
+			t.Errorf("%s: unexpected iframe warning %+v", f.Name, w)
+		}
+		if w := warnings[1]; w.Element != "input" || w.Path != "html/body/form/input" {
+			t.Errorf("%s: unexpected input warning %+v", f.Name, w)
+		}
+	}
+}
+
+func TestDocxConverterImageWarning(t *testing.T) {
+	conv := NewHTMLToDocxConverter()
+	if err := conv.Convert([]string{`<html><body><p><img src="logo.png"></p></body></html>`}); err != nil {
+		t.Fatalf("Convert failed: %v", err)
+	}
+	if w := conv.Warnings(); len(w) != 1 || w[0].Element != "img" {
+		t.Errorf("expected an img warning, got %v", w)
+	}
+}
+
+func TestPDFConverterHeaderWarnedOnce(t *testing.T) {
+	conv := NewHTMLToPDFConverter()
+	err := conv.Convert([]string{`<html><body>
+		<header><img src="does-not-exist.png"></header>
+		` + strings.Repeat("<p>Body paragraph that fills the page.</p>", 120) + `
+	</body></html>`})
+	if err != nil {
+		t.Fatalf("Convert failed: %v", err)
+	}
+	if conv.pdf.PageNo() < 2 {
+		t.Fatal("expected several pages")
+	}
+	if w := conv.Warnings(); len(w) != 1 || w[0].Reason != "image could not be loaded" {
+		t.Errorf("expected one image warning, got %v", w)
+	}
+}
+
+func TestStrictMode(t *testing.T) {
+	for _, f := range Formats() {
+		conv := f.New(Options{Strict: true})
+		err := conv.ConvertSections(HTMLSections([]string{warningsHTML}))
+		var we *WarningError
+		if !errors.As(err, &we) || we.Warning.Element != "iframe" {
+			t.Errorf("%s: expected a warning error for the iframe, got %v", f.Name, err)
+		}
+	}
+}
@@ -4,6 +4,13 @@ go 1.25.5
 
 require (
 	baliance.com/gooxml v1.0.1
+	github.com/BurntSushi/toml v1.5.0
+	github.com/fsnotify/fsnotify v1.9.0
 	github.com/jung-kurt/gofpdf v1.16.2
+	golang.org/x/image v0.25.0
 	golang.org/x/net v0.49.0
+	golang.org/x/text v0.33.0
+	gopkg.in/yaml.v3 v3.0.1
 )
+
+require golang.org/x/sys v0.40.0 // indirect